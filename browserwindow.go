@@ -26,6 +26,10 @@ func (i *item) Format(width int) string {
 	return i.fmtr.Format(i.data, width)
 }
 
+func (i *item) FormatSpans(width int) []format.Span {
+	return i.fmtr.FormatSpans(i.data, width)
+}
+
 func (i *item) IsActive(val string) bool {
 	if i.entry.IsDir() {
 		d := i.entry.Dir()
@@ -47,11 +51,13 @@ type BrowserWindow struct {
 func NewBrowserWindow(h, w, y, x int) (*BrowserWindow, error) {
 	list, err := NewListWindow(h, w, y, x)
 	return &BrowserWindow{
-		path:      "",
-		list:      list,
-		items:     nil,
-		dirFmtr:   format.NewFormatter(config.FormatBrowserDir),
-		trackFmtr: format.NewFormatter(config.FormatBrowserTrack),
+		path:  "",
+		list:  list,
+		items: nil,
+		dirFmtr: format.NewFormatter(config.FormatBrowserDir,
+			config.DirVerbs),
+		trackFmtr: format.NewFormatter(config.FormatBrowserTrack,
+			config.TrackVerbs),
 	}, err
 }
 
@@ -74,8 +80,8 @@ func (w *BrowserWindow) SetDir(p string, entries []chubby.Entry) error {
 		Name: "..",
 	}
 	items = append(items, newItem(pd, map[string]string{
-		"p": pd.Path,
-		"n": "..",
+		"path": pd.Path,
+		"name": "..",
 	}, w.dirFmtr))
 	parent := -1
 
@@ -86,20 +92,13 @@ func (w *BrowserWindow) SetDir(p string, entries []chubby.Entry) error {
 
 		if e.IsDir() {
 			data = map[string]string{
-				"p": e.Dir().Path,
-				"n": e.Dir().Name,
+				"path": e.Dir().Path,
+				"name": e.Dir().Name,
 			}
 			fmtr = w.dirFmtr
 			path = e.Dir().Path
 		} else {
-			data = map[string]string{
-				"p": e.Track().Path,
-				"a": e.Track().Artist,
-				"b": e.Track().Album,
-				"t": e.Track().Title,
-				"n": strconv.Itoa(e.Track().Number),
-				"l": e.Track().Length.String(),
-			}
+			data = trackData(e.Track())
 			fmtr = w.trackFmtr
 			path = e.Track().Path
 		}
@@ -149,6 +148,10 @@ func (w *BrowserWindow) PageDown() {
 	w.list.PageDown()
 }
 
+func (w *BrowserWindow) Goto(i int) {
+	w.list.Goto(i)
+}
+
 func (w *BrowserWindow) Home() {
 	w.list.Home()
 }
@@ -157,10 +160,59 @@ func (w *BrowserWindow) End() {
 	w.list.End()
 }
 
+func trackData(t *chubby.Track) map[string]string {
+	return map[string]string{
+		"path":     t.Path,
+		"filename": filepath.Base(t.Path),
+		"artist":   t.Artist,
+		"album":    t.Album,
+		"title":    t.Title,
+		"track":    strconv.Itoa(t.Number),
+		"length":   t.Length.String(),
+	}
+}
+
 func isParent(path, parent string) bool {
 	return strings.HasPrefix(path, parent)
 }
 
+// Tagged returns all tagged entries.
+func (w *BrowserWindow) Tagged() []chubby.Entry {
+	var es []chubby.Entry
+	for _, it := range w.list.Tagged() {
+		e := it.(*item).entry
+		if !e.IsDir() || e.Dir().Name != ".." {
+			es = append(es, e)
+		}
+	}
+
+	return es
+}
+
+func (w *BrowserWindow) Tag() {
+	w.list.Tag()
+}
+
+func (w *BrowserWindow) TagRange() {
+	w.list.TagRange()
+}
+
+func (w *BrowserWindow) InvertTags() {
+	w.list.InvertTags()
+}
+
+func (w *BrowserWindow) TagPattern(pattern string) error {
+	return w.list.TagPattern(pattern)
+}
+
+func (w *BrowserWindow) ClearTags() {
+	w.list.ClearTags()
+}
+
+func (w *BrowserWindow) SetHidden(hidden bool) {
+	w.list.SetHidden(hidden)
+}
+
 func (w *BrowserWindow) Delete() {
 	w.list.Delete()
 }
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+)
+
+// copyToClipboard puts text into the system clipboard using terminal's
+// OSC 52 escape sequence. It works over SSH too, but terminal should
+// support it.
+func copyToClipboard(s string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(s)) + "\a"
+	_, err := os.Stdout.WriteString(seq)
+
+	return err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/chubby"
+	ctime "github.com/vchimishuk/chubby/time"
+)
+
+// commandError is an error caused by invalid command entered by user.
+type commandError struct {
+	msg string
+}
+
+func newCommandError(format string, args ...any) *commandError {
+	return &commandError{fmt.Sprintf(format, args...)}
+}
+
+func (e *commandError) Error() string {
+	return e.msg
+}
+
+// runCommand parses and executes command line entered by user.
+// Command line consists of a command name followed by its arguments
+// separated by spaces. Every command which can be bound to a key can be
+// executed from the command line as well.
+func runCommand(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	a, err := config.ParseAction(line)
+	if err != nil {
+		return newCommandError("%s", err)
+	}
+	err = execAction(a)
+	if chubby.IsServerError(err) {
+		return newCommandError("%s: %s", a.Cmd, err)
+	}
+
+	return err
+}
+
+// seek seeks current track to the given position in the seek command
+// argument format.
+func seek(arg string) error {
+	sk, err := config.ParseSeek(arg)
+	if err != nil {
+		return newCommandError("seek: %s", err)
+	}
+
+	t := sk.Time
+	if sk.Percent {
+		st := chubStatus
+		if st == nil || st.Track == nil {
+			return newCommandError("seek: nothing is playing")
+		}
+		t = ctime.New(int(st.Track.Length) * int(sk.Time) / 100)
+	}
+	submit("seek", func(c *chubby.Chubby) error {
+		return c.Seek(t, sk.Mode)
+	}, nil)
+
+	return nil
+}
+
+// completeCommand completes command names and their arguments.
+func completeCommand(s string) []string {
+	head, word := completionWord(s)
+	if head == "" {
+		return completeName(word, commandNames())
+	}
+	fields := strings.Fields(head)
+	if len(fields) == 0 {
+		return nil
+	}
+	var cands []string
+
+	switch {
+	case fields[0] == string(config.CmdCd) && len(fields) == 1:
+		cands = completePath(word)
+	case fields[0] == string(config.CmdBind) && len(fields) == 2:
+		cands = completeName(word, commandNames())
+	case fields[0] == string(config.CmdServer) && len(fields) == 1:
+		cands = completeName(word, config.ServerNames())
+	}
+
+	for i := range cands {
+		cands[i] = head + cands[i]
+	}
+
+	return cands
+}
+
+// completionWord splits command line into the head and the word being
+// completed. The word is the last space separated field, except for
+// cd command which path argument is the rest of the line.
+func completionWord(s string) (head string, word string) {
+	i := strings.LastIndex(s, " ")
+	if i == -1 {
+		return "", s
+	}
+
+	cd := string(config.CmdCd)
+	fields := strings.Fields(s)
+	if len(fields) > 0 && fields[0] == cd {
+		rest := strings.TrimLeft(s, " ")[len(cd):]
+		if strings.HasPrefix(rest, " ") {
+			j := len(s) - len(strings.TrimLeft(rest, " "))
+			return s[:j], s[j:]
+		}
+	}
+
+	return s[:i+1], s[i+1:]
+}
+
+func commandNames() []string {
+	var names []string
+	for _, c := range config.Commands() {
+		names = append(names, string(c))
+	}
+
+	return names
+}
+
+func completeName(prefix string, names []string) []string {
+	var cands []string
+	for _, n := range names {
+		if strings.HasPrefix(n, prefix) {
+			cands = append(cands, n)
+		}
+	}
+
+	return cands
+}
+
+// completePath completes directory path using server's VFS.
+func completePath(p string) []string {
+	var dir, base string
+	if p == "" || strings.HasSuffix(p, "/") {
+		dir = p
+	} else {
+		dir = path.Dir(p)
+		base = path.Base(p)
+	}
+	prefix := p[:len(p)-len(base)]
+
+	var es []chubby.Entry
+	err := callSync(func(c *chubby.Chubby) error {
+		var err error
+		es, err = c.List(resolvePath(dir))
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+
+	var cands []string
+	for _, e := range es {
+		if e.IsDir() && strings.HasPrefix(e.Dir().Name, base) {
+			cands = append(cands, prefix+e.Dir().Name+"/")
+		}
+	}
+
+	return cands
+}
+
+// resolvePath returns absolute VFS path. Relative paths are resolved
+// against the current browser directory.
+func resolvePath(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+
+	return path.Join(browserPath, p)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompletionWord(t *testing.T) {
+	tests := []struct {
+		s    string
+		head string
+		word string
+	}{
+		{"se", "", "se"},
+		{"seek ", "seek ", ""},
+		{"bind x se", "bind x ", "se"},
+		{"cd ", "cd ", ""},
+		{"cd /music/some ar", "cd ", "/music/some ar"},
+		{"  cd  /music/some ar", "  cd  ", "/music/some ar"},
+		{"cdx y", "cdx ", "y"},
+		{" ", " ", ""},
+	}
+	for _, test := range tests {
+		head, word := completionWord(test.s)
+		if head != test.head || word != test.word {
+			t.Errorf("Completion word of \"%s\" expected (%q, %q), "+
+				"got (%q, %q)", test.s, test.head, test.word,
+				head, word)
+		}
+	}
+}
+
+func TestCompleteCommandSpaces(t *testing.T) {
+	for _, s := range []string{" ", "   ", "\t "} {
+		if cands := completeCommand(s); cands != nil {
+			t.Errorf("No completions expected for %q, got %q",
+				s, cands)
+		}
+	}
+}
@@ -22,14 +22,20 @@ const (
 	KEY_NAK              = 0x15 // ^U
 	KEY_SOH              = 0x01 // ^A
 	KEY_STX              = 0x02 // ^B
+	KEY_TAB              = 0x09 // ^I
 	KEY_VT               = 0x0B // ^K
 	KEY_BS2              = 0x7F // BACKSPACE in some situations.
 )
 
+// Width of the pending keys area at the right side of the window.
+const keysWidth = 10
+
 type CommandWindow struct {
 	window  *ncurses.Window
 	cursorY int
 	cursorX int
+	// Pending keys of incomplete key sequence.
+	keys string
 }
 
 func NewCommandWindow(w, y, x int) (*CommandWindow, error) {
@@ -38,42 +44,89 @@ func NewCommandWindow(w, y, x int) (*CommandWindow, error) {
 		return nil, err
 	}
 	window.Keypad(true)
+	// Input waits for keys with timeout to let UI events be processed
+	// meanwhile.
+	window.Timeout(int(pollTimeout.Milliseconds()))
 	window.AttrOn(config.ColorNormal)
 
 	return &CommandWindow{window: window}, nil
 }
 
+// Replace takes over the window of n, which is created to fit the new
+// terminal size. Active input continues in the new window.
+func (w *CommandWindow) Replace(n *CommandWindow) {
+	w.window = n.window
+	w.keys = ""
+	if w.active() {
+		ncurses.Cursor(1)
+	}
+}
+
 func (w *CommandWindow) Delete() {
 	w.window.Delete()
 }
 
 func (w *CommandWindow) Refresh() {
 	// Only if we are active.
-	if w.cursorY != 0 || w.cursorX != 0 {
+	if w.active() {
 		w.window.Move(w.cursorY, w.cursorX)
 		w.window.Refresh()
 	}
 }
 
-func (w *CommandWindow) Input(prompt string) string {
-	prompt += " "
+// ShowKeys displays pending keys of incomplete key sequence at
+// the right side of the window.
+func (w *CommandWindow) ShowKeys(keys string) {
+	// Input is active, do not mess it up.
+	if w.active() {
+		return
+	}
+	if keys == w.keys {
+		return
+	}
+	w.keys = keys
 
+	if len(keys) > keysWidth {
+		keys = keys[len(keys)-keysWidth:]
+	}
+	w.window.MovePrint(0, w.maxX()-keysWidth,
+		strings.Repeat(" ", keysWidth-len(keys))+keys)
+	w.window.Refresh()
+}
+
+// Completer returns list of possible completions for the given text
+// before the cursor. Every completion replaces the whole text.
+type Completer func(s string) []string
+
+// Input reads a line of text from the user. If complete is not nil
+// it is used to complete text on Tab key press. Sequential Tab presses
+// cycle through all available completions.
+func (w *CommandWindow) Input(prompt string, complete Completer) string {
 	ncurses.Cursor(1)
-	// Width of edit area.
-	width := w.maxX() - len(prompt)
 	// Cursor position in window.
 	x := 0
 	// First index of uffer visible on screen.
 	o := 0
 	buf := ""
+	// Completions for the current Tab series and the text after
+	// the cursor when completion was started.
+	var cands []string
+	var candIdx int
+	var candTail string
 
 loop:
 	for {
+		// Width of edit area. Terminal can be resized while
+		// waiting for input.
+		width := max(1, w.maxX()-len(prompt))
+		if x >= width {
+			o += x - width + 1
+			x = width - 1
+		}
 		s := buf[o:min(o+width, len(buf))]
 		// Buffer offset, cursor position in buffer.
 		bo := o + x
 
-		NcursesMu.Lock()
 		w.window.MovePrint(0, 0, prompt)
 		w.window.Print(s)
 		if len(s) < width {
@@ -82,13 +135,20 @@ loop:
 		w.window.Move(0, len(prompt)+x)
 		w.cursorY, w.cursorX = w.window.CursorYX()
 		w.window.Refresh()
-		NcursesMu.Unlock()
 
 		ch := w.window.GetChar()
 
-		if ch == 0 {
+		// Resize is handled by the UI event, so window is
+		// already replaced.
+		if ch == 0 || ch == ncurses.KEY_RESIZE {
+			processEvents()
 			continue
-		} else if ch == ncurses.KEY_RETURN {
+		}
+		if ch != KEY_TAB {
+			cands = nil
+		}
+
+		if ch == ncurses.KEY_RETURN {
 			break
 		} else if ch == KEY_SOH || ch == ncurses.KEY_HOME {
 			o = 0
@@ -158,8 +218,33 @@ loop:
 			o = 0
 		} else if ch == KEY_VT {
 			buf = buf[:bo]
+		} else if ch == KEY_TAB && complete != nil {
+			var repl string
+			if cands == nil {
+				cands = complete(buf[:bo])
+				candIdx = -1
+				candTail = buf[bo:]
+				if len(cands) == 1 {
+					repl = cands[0]
+					cands = nil
+				} else if len(cands) > 1 {
+					repl = commonPrefix(cands)
+					if len(repl) <= bo {
+						candIdx = 0
+						repl = cands[candIdx]
+					}
+				}
+			} else {
+				candIdx = (candIdx + 1) % len(cands)
+				repl = cands[candIdx]
+			}
+			if repl != "" {
+				buf = repl + candTail
+				o = max(0, len(repl)-width+1)
+				x = len(repl) - o
+			}
 		} else if unicode.IsPrint(rune(ch)) {
-			buf = buf[:bo] + string(ch) + buf[bo:]
+			buf = buf[:bo] + string(rune(ch)) + buf[bo:]
 			x++
 		}
 
@@ -176,17 +261,20 @@ loop:
 		}
 	}
 
-	NcursesMu.Lock()
 	ncurses.Cursor(0)
 	w.erase()
 	w.window.Refresh()
 	w.cursorY = 0
 	w.cursorX = 0
-	NcursesMu.Unlock()
 
 	return buf
 }
 
+// active returns true if input is in progress.
+func (w *CommandWindow) active() bool {
+	return w.cursorY != 0 || w.cursorX != 0
+}
+
 func (w *CommandWindow) erase() {
 	w.window.MovePrint(0, 0, strings.Repeat(" ", w.maxX()))
 
@@ -198,6 +286,19 @@ func (w *CommandWindow) maxX() int {
 	return x
 }
 
+func commonPrefix(ss []string) string {
+	p := ss[0]
+	for _, s := range ss[1:] {
+		i := 0
+		for i < len(p) && i < len(s) && p[i] == s[i] {
+			i++
+		}
+		p = p[:i]
+	}
+
+	return p
+}
+
 func wordBegin(s string, pos int) int {
 	trim := true
 	done := false
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vchimishuk/chubby"
+	ctime "github.com/vchimishuk/chubby/time"
+)
+
+// Action is a command together with its arguments.
+type Action struct {
+	Cmd  Cmd
+	Args []string
+}
+
+func (a Action) String() string {
+	return strings.Join(append([]string{string(a.Cmd)}, a.Args...), " ")
+}
+
+// Seek is a parsed seek command argument.
+type Seek struct {
+	Mode chubby.SeekMode
+	// Time to seek to or by. It is a percent of the track length
+	// if Percent is set.
+	Time    ctime.Time
+	Percent bool
+}
+
+// ParseAction parses command line which consists of a command name
+// followed by its arguments separated by spaces, e.g. "seek-forward 60".
+func ParseAction(s string) (Action, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Action{}, errors.New("command expected")
+	}
+
+	cmd, err := ParseCmd(fields[0])
+	if err != nil {
+		return Action{}, err
+	}
+	args := fields[1:]
+	// Path can contain spaces, so the rest of the line is cd argument.
+	if cmd == CmdCd && len(args) > 1 {
+		rest := strings.TrimSpace(s)[len(fields[0]):]
+		args = []string{strings.TrimSpace(rest)}
+	}
+	err = validateArgs(cmd, args)
+	if err != nil {
+		return Action{}, fmt.Errorf("%s: %w", cmd, err)
+	}
+
+	return Action{Cmd: cmd, Args: args}, nil
+}
+
+// ParseSeek parses seek command argument. Position can be given in
+// seconds, in [[hh:]mm:]ss format or in percents of the track length
+// with % suffix. Plus or minus prefix makes seek relative to the current
+// position.
+func ParseSeek(s string) (Seek, error) {
+	sk := Seek{Mode: chubby.SeekModeAbs}
+	if strings.HasPrefix(s, "+") {
+		sk.Mode = chubby.SeekModeForward
+		s = s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		sk.Mode = chubby.SeekModeBackward
+		s = s[1:]
+	}
+
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || p < 0 || p > 100 {
+			return Seek{}, fmt.Errorf("invalid percent: %s", s)
+		}
+		sk.Time = ctime.New(p)
+		sk.Percent = true
+
+		return sk, nil
+	}
+
+	t, err := ParseTime(s)
+	if err != nil {
+		return Seek{}, err
+	}
+	sk.Time = t
+
+	return sk, nil
+}
+
+// ParseTime parses time given in seconds or in [[hh:]mm:]ss format.
+func ParseTime(s string) (ctime.Time, error) {
+	if strings.Contains(s, ":") {
+		t, err := ctime.Parse(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time: %s", s)
+		}
+
+		return t, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid time: %s", s)
+	}
+
+	return ctime.New(i), nil
+}
+
+// ParseVolume parses volume level in percents. Plus or minus prefix
+// makes volume change relative.
+func ParseVolume(s string) (int, chubby.VolumeMode, error) {
+	mode := chubby.VolumeMode(chubby.VolumeModeAbs)
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		mode = chubby.VolumeModeRel
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, mode, fmt.Errorf("invalid volume: %s", s)
+	}
+	if mode == chubby.VolumeModeAbs && (v < 0 || v > 100) {
+		return 0, mode, fmt.Errorf("volume out of range: %d", v)
+	}
+
+	return v, mode, nil
+}
+
+// ParseStep parses positive integer step of volume change.
+func ParseStep(s string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid step: %s", s)
+	}
+
+	return i, nil
+}
+
+func validateArgs(cmd Cmd, args []string) error {
+	var err error
+
+	switch cmd {
+	case CmdBind:
+		if len(args) < 2 {
+			return errors.New("usage: bind KEY COMMAND [ARG]...")
+		}
+		_, err = ParseKey(args[0])
+		if err == nil {
+			_, err = ParseAction(strings.Join(args[1:], " "))
+		}
+	case CmdCd:
+		if len(args) != 1 {
+			return errors.New("usage: cd PATH")
+		}
+	case CmdSeek:
+		if len(args) != 1 {
+			return errors.New("usage: seek [+|-]TIME[%]")
+		}
+		_, err = ParseSeek(args[0])
+	case CmdSeekBackward, CmdSeekForward:
+		if len(args) > 1 {
+			return fmt.Errorf("usage: %s [TIME]", cmd)
+		}
+		if len(args) == 1 {
+			_, err = ParseTime(args[0])
+		}
+	case CmdServer:
+		if len(args) > 1 {
+			return errors.New("usage: server [NAME]")
+		}
+	case CmdVolume:
+		if len(args) != 1 {
+			return errors.New("usage: volume [+|-]VOLUME")
+		}
+		_, _, err = ParseVolume(args[0])
+	case CmdVolumeDown, CmdVolumeUp:
+		if len(args) > 1 {
+			return fmt.Errorf("usage: %s [STEP]", cmd)
+		}
+		if len(args) == 1 {
+			_, err = ParseStep(args[0])
+		}
+	default:
+		if len(args) != 0 {
+			return errors.New("no arguments expected")
+		}
+	}
+
+	return err
+}
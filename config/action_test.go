@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/vchimishuk/chubby"
+)
+
+func testParseAction(t *testing.T, s string, expected string) {
+	a, err := ParseAction(s)
+	if err != nil {
+		t.Errorf("Error parsing action \"%s\". %s", s, err)
+	} else if a.String() != expected {
+		t.Errorf("Action parse error.\nExpected: '%s'\nActual: '%s'",
+			expected, a.String())
+	}
+}
+
+func testParseActionErr(t *testing.T, s string) {
+	_, err := ParseAction(s)
+	if err == nil {
+		t.Errorf("Error expected parsing action \"%s\".", s)
+	}
+}
+
+func testParseSeek(t *testing.T, s string, expected Seek) {
+	sk, err := ParseSeek(s)
+	if err != nil {
+		t.Errorf("Error parsing seek \"%s\". %s", s, err)
+	} else if sk != expected {
+		t.Errorf("Seek parse error.\nExpected: %v\nActual: %v",
+			expected, sk)
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	testParseAction(t, "stop", "stop")
+	testParseAction(t, "  seek-forward   60 ", "seek-forward 60")
+	testParseAction(t, "volume 50", "volume 50")
+	testParseAction(t, "volume -5", "volume -5")
+	testParseAction(t, "seek 1:30", "seek 1:30")
+	testParseAction(t, "bind x seek-forward 10", "bind x seek-forward 10")
+	testParseAction(t, "bind gg home", "bind gg home")
+	testParseAction(t, "cd /music/some  artist ", "cd /music/some  artist")
+
+	testParseActionErr(t, "")
+	testParseActionErr(t, "foo")
+	testParseActionErr(t, "stop now")
+	testParseActionErr(t, "seek")
+	testParseActionErr(t, "seek-forward -1")
+	testParseActionErr(t, "volume 101")
+	testParseActionErr(t, "volume-up 0")
+	testParseActionErr(t, "bind x")
+	testParseActionErr(t, "bind x seek")
+}
+
+func TestParseActionCdSpaces(t *testing.T) {
+	a, err := ParseAction("cd /music/some artist/")
+	if err != nil {
+		t.Fatalf("Error parsing cd action. %s", err)
+	}
+	if len(a.Args) != 1 || a.Args[0] != "/music/some artist/" {
+		t.Errorf("Single cd argument expected, got %q", a.Args)
+	}
+}
+
+func TestParseSeek(t *testing.T) {
+	testParseSeek(t, "90", Seek{chubby.SeekModeAbs, 90, false})
+	testParseSeek(t, "1:30", Seek{chubby.SeekModeAbs, 90, false})
+	testParseSeek(t, "+30", Seek{chubby.SeekModeForward, 30, false})
+	testParseSeek(t, "-1:00", Seek{chubby.SeekModeBackward, 60, false})
+	testParseSeek(t, "50%", Seek{chubby.SeekModeAbs, 50, true})
+	testParseSeek(t, "+10%", Seek{chubby.SeekModeForward, 10, true})
+}
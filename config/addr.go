@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Addr is a chub server address. Server listens either on TCP Host
+// and Port or on Unix domain socket Path.
+type Addr struct {
+	Host string
+	Port int
+	Path string
+}
+
+// Unix returns true if the address is a Unix domain socket.
+func (a Addr) Unix() bool {
+	return a.Path != ""
+}
+
+func (a Addr) String() string {
+	if a.Unix() {
+		return "unix:" + a.Path
+	}
+
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// ParseAddr parses server address given in unix:PATH, HOST:PORT or
+// [IPV6]:PORT form. Port can be omitted, in which case the given
+// default port is used, e.g. "localhost" or "::1".
+func ParseAddr(s string, port int) (Addr, error) {
+	if p, ok := strings.CutPrefix(s, "unix:"); ok {
+		if p == "" {
+			return Addr{}, errors.New("socket path expected")
+		}
+		return Addr{Path: p}, nil
+	}
+	if s == "" {
+		return Addr{}, errors.New("host expected")
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return Addr{Host: s[1 : len(s)-1], Port: port}, nil
+	}
+	// Bare IPv6 address has more than one colon.
+	if c := strings.Count(s, ":"); c == 0 ||
+		(c > 1 && !strings.HasPrefix(s, "[")) {
+
+		return Addr{Host: s, Port: port}, nil
+	}
+
+	host, ps, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return Addr{}, fmt.Errorf("invalid address: %s", s)
+	}
+	p, err := strconv.Atoi(ps)
+	if err != nil || p <= 0 || p > 65535 {
+		return Addr{}, fmt.Errorf("invalid port: %s", ps)
+	}
+
+	return Addr{Host: host, Port: p}, nil
+}
+
+func parseAddr(v any) (any, error) {
+	_, err := ParseAddr(v.(string), DefaultPort)
+
+	return v, escapeError(err)
+}
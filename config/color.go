@@ -0,0 +1,421 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ncurses "github.com/gbin/goncurses"
+	"github.com/vchimishuk/asp/format"
+	"github.com/vchimishuk/config"
+)
+
+var colorNames = map[string]int16{
+	"black":   ncurses.C_BLACK,
+	"blue":    ncurses.C_BLUE,
+	"cyan":    ncurses.C_CYAN,
+	"green":   ncurses.C_GREEN,
+	"magenta": ncurses.C_MAGENTA,
+	"red":     ncurses.C_RED,
+	"white":   ncurses.C_WHITE,
+	"yellow":  ncurses.C_YELLOW,
+}
+
+var attrNames = map[string]ncurses.Char{
+	"blink":     ncurses.A_BLINK,
+	"bold":      ncurses.A_BOLD,
+	"dim":       ncurses.A_DIM,
+	"reverse":   ncurses.A_REVERSE,
+	"standout":  ncurses.A_STANDOUT,
+	"underline": ncurses.A_UNDERLINE,
+}
+
+// colorDefault is a terminal default color.
+const colorDefault int16 = -1
+
+// color is a single terminal color. It is either a palette index
+// or, if rgb is true, a true color value.
+type color struct {
+	index   int16
+	rgb     bool
+	r, g, b uint8
+}
+
+// colorSpec describes how a UI element is displayed.
+type colorSpec struct {
+	fg    color
+	bg    color
+	attrs ncurses.Char
+}
+
+// parseColorSpec parses color property value in "fg:bg[:attr,...]" form.
+// Color is a basic color name, "default" for terminal default color,
+// 256-color palette index or #rrggbb value, e.g. "default:236:bold".
+func parseColorSpec(s string) (colorSpec, error) {
+	var cs colorSpec
+	pts := strings.Split(s, ":")
+	if len(pts) < 2 || len(pts) > 3 {
+		return cs, errors.New("invalid color pair")
+	}
+	fg, err := parseColorValue(pts[0])
+	if err != nil {
+		return cs, err
+	}
+	bg, err := parseColorValue(pts[1])
+	if err != nil {
+		return cs, err
+	}
+	cs.fg = fg
+	cs.bg = bg
+	if len(pts) == 3 {
+		for _, a := range strings.Split(pts[2], ",") {
+			attr, ok := attrNames[strings.TrimSpace(a)]
+			if !ok {
+				return cs, fmt.Errorf("invalid attribute: %s", a)
+			}
+			cs.attrs |= attr
+		}
+	}
+
+	return cs, nil
+}
+
+// parseColorValue parses single color.
+func parseColorValue(s string) (color, error) {
+	if s == "default" {
+		return color{index: colorDefault}, nil
+	}
+	if c, ok := colorNames[s]; ok {
+		return color{index: c}, nil
+	}
+	if strings.HasPrefix(s, "#") {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil || len(s) != 7 {
+			return color{}, fmt.Errorf("invalid color: %s", s)
+		}
+		return color{rgb: true, r: uint8(v >> 16), g: uint8(v >> 8),
+			b: uint8(v)}, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i > 255 {
+		return color{}, fmt.Errorf("invalid color: %s", s)
+	}
+
+	return color{index: int16(i)}, nil
+}
+
+func parseColor(v any) (any, error) {
+	cs, err := parseColorSpec(v.(string))
+
+	return cs, escapeError(err)
+}
+
+// Channel values of the xterm 256-color cube levels.
+var cubeLevels = []int{0, 95, 135, 175, 215, 255}
+
+// palette256 returns the closest xterm 256-color palette index.
+func (c color) palette256() int16 {
+	ci := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := ci(c.r), ci(c.g), ci(c.b)
+	cube := 16 + 36*r + 6*g + b
+	cubeDist := dist(c, cubeLevels[r], cubeLevels[g], cubeLevels[b])
+
+	// Grayscale ramp: 232-255, from 8 to 238 with step 10.
+	avg := (int(c.r) + int(c.g) + int(c.b)) / 3
+	gi := (avg - 8 + 5) / 10
+	if gi < 0 {
+		gi = 0
+	} else if gi > 23 {
+		gi = 23
+	}
+	gv := 8 + 10*gi
+	if dist(c, gv, gv, gv) < cubeDist {
+		return int16(232 + gi)
+	}
+
+	return int16(cube)
+}
+
+// indexColor returns true color of the xterm 256-color palette index
+// greater than 15. Lower indexes are basic colors which depend on
+// the terminal.
+func indexColor(i int16) color {
+	if i >= 232 {
+		v := uint8(8 + 10*(i-232))
+		return color{rgb: true, r: v, g: v, b: v}
+	}
+	i -= 16
+
+	return color{rgb: true, r: uint8(cubeLevels[i/36]),
+		g: uint8(cubeLevels[i/6%6]), b: uint8(cubeLevels[i%6])}
+}
+
+// palette8 returns the closest basic color.
+func (c color) palette8() int16 {
+	var i int16
+	if c.r > 127 {
+		i |= ncurses.C_RED
+	}
+	if c.g > 127 {
+		i |= ncurses.C_GREEN
+	}
+	if c.b > 127 {
+		i |= ncurses.C_BLUE
+	}
+
+	return i
+}
+
+func dist(c color, r, g, b int) int {
+	dr := int(c.r) - r
+	dg := int(c.g) - g
+	db := int(c.b) - b
+
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
+
+// colorAllocator assigns palette entries to true colors. Palette is
+// redefined starting from the last 256-color palette entry down,
+// where it is least likely to be noticed.
+type colorAllocator struct {
+	next  int16
+	alloc map[color]int16
+}
+
+func newColorAllocator() *colorAllocator {
+	return &colorAllocator{next: 255, alloc: make(map[color]int16)}
+}
+
+// candidates returns palette indexes to try for the color, from
+// the most precise to the most compatible one.
+func (a *colorAllocator) candidates(c color) []int16 {
+	if !c.rgb {
+		switch {
+		case c.index >= 16:
+			return []int16{c.index, indexColor(c.index).palette8()}
+		case c.index >= 8:
+			// Bright version of the basic color.
+			return []int16{c.index, c.index - 8}
+		default:
+			return []int16{c.index}
+		}
+	}
+	var res []int16
+	if i, ok := a.alloc[c]; ok {
+		res = append(res, i)
+	} else if ncurses.CanChangeColor() && a.next >= 16 {
+		err := ncurses.InitColor(a.next, int16(int(c.r)*1000/255),
+			int16(int(c.g)*1000/255), int16(int(c.b)*1000/255))
+		if err == nil {
+			a.alloc[c] = a.next
+			res = append(res, a.next)
+			a.next--
+		}
+	}
+
+	return append(res, c.palette256(), c.palette8())
+}
+
+// initPair initializes color pair with the closest colors the terminal
+// supports. It returns background palette index actually used.
+func (a *colorAllocator) initPair(id int16, cs colorSpec) (int16, error) {
+	if cs.fg.index == colorDefault || cs.bg.index == colorDefault {
+		if err := ncurses.UseDefaultColors(); err != nil {
+			return 0, errors.New("default colors are not supported")
+		}
+	}
+	fgs := a.candidates(cs.fg)
+	bgs := a.candidates(cs.bg)
+	var err error
+	for i := 0; i < max(len(fgs), len(bgs)); i++ {
+		fg := fgs[min(i, len(fgs)-1)]
+		bg := bgs[min(i, len(bgs)-1)]
+		err = ncurses.InitPair(id, fg, bg)
+		if err == nil {
+			return bg, nil
+		}
+	}
+
+	return 0, err
+}
+
+// firstStylePair is the first color pair used for the format style
+// markup. Lower pairs are used by the UI elements.
+const firstStylePair int16 = 10
+
+// elemColor describes how UI element color pair was initialized.
+type elemColor struct {
+	bg    int16
+	attrs ncurses.Char
+}
+
+// styleKey identifies color pair allocated for the style markup.
+type styleKey struct {
+	fg color
+	bg int16
+}
+
+var (
+	// colorSpecs are color specs of UI elements applied last.
+	colorSpecs []colorSpec
+	allocator  *colorAllocator
+	elemColors map[ncurses.Char]elemColor
+	stylePairs map[styleKey]int16
+	nextPair   int16
+)
+
+var styleAttrs = map[format.Attr]ncurses.Char{
+	format.AttrBlink:     ncurses.A_BLINK,
+	format.AttrBold:      ncurses.A_BOLD,
+	format.AttrDim:       ncurses.A_DIM,
+	format.AttrReverse:   ncurses.A_REVERSE,
+	format.AttrStandout:  ncurses.A_STANDOUT,
+	format.AttrUnderline: ncurses.A_UNDERLINE,
+}
+
+// StyleAttr returns attributes to display text with the style set by
+// format markup inside UI element with base attributes, e.g. ColorList.
+// Foreground color of the style is combined with the element background.
+// Element color is kept if the terminal can not display the style color.
+func StyleAttr(base ncurses.Char, st format.Style) ncurses.Char {
+	var attrs ncurses.Char
+	for a, na := range styleAttrs {
+		if st.Attrs&a != 0 {
+			attrs |= na
+		}
+	}
+	ec, ok := elemColors[base]
+	if st.Fg == "" || !ok {
+		return base | attrs
+	}
+	fg, err := parseColorValue(st.Fg)
+	if err != nil {
+		return base | attrs
+	}
+
+	key := styleKey{fg, ec.bg}
+	id, ok := stylePairs[key]
+	if !ok {
+		// Failures are remembered too, not to retry on every refresh.
+		id = -1
+		cs := colorSpec{fg: fg, bg: color{index: ec.bg}}
+		if _, err := allocator.initPair(nextPair, cs); err == nil {
+			id = nextPair
+			nextPair++
+		}
+		stylePairs[key] = id
+	}
+	if id == -1 {
+		return base | attrs
+	}
+
+	return ncurses.ColorPair(id) | ec.attrs | attrs
+}
+
+// initColors initializes color pairs of all UI elements. Colors set
+// in the configuration file take precedence over the theme ones.
+// Previously applied colors are restored if the new ones fail.
+func initColors(cfg *config.Config, theme *config.Config) error {
+	specs := make([]colorSpec, len(defColors))
+	for i, c := range defColors {
+		def, err := parseColorSpec(c.Def)
+		if err != nil {
+			return err
+		}
+		specs[i] = cfg.AnyOr(c.Name, theme.AnyOr(c.Name, def)).(colorSpec)
+	}
+
+	err := applyColors(specs)
+	if err != nil {
+		if colorSpecs != nil {
+			applyColors(colorSpecs)
+		}
+		return err
+	}
+	colorSpecs = specs
+
+	return nil
+}
+
+// applyColors initializes color pairs of UI elements with the given
+// color specs in defColors order. Package color state is changed only
+// if all pairs are initialized.
+func applyColors(specs []colorSpec) error {
+	alloc := newColorAllocator()
+	elems := make(map[ncurses.Char]elemColor)
+	vars := make([]ncurses.Char, len(defColors))
+	for i, c := range defColors {
+		bg, err := alloc.initPair(c.ID, specs[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		vars[i] = ncurses.ColorPair(c.ID) | specs[i].attrs
+		elems[vars[i]] = elemColor{bg, specs[i].attrs}
+	}
+
+	allocator = alloc
+	elemColors = elems
+	stylePairs = make(map[styleKey]int16)
+	nextPair = firstStylePair
+	for i, c := range defColors {
+		*c.Var = vars[i]
+	}
+
+	return nil
+}
+
+// themeSpec is a specification of theme files. Theme file can contain
+// color properties only.
+var themeSpec = &config.Spec{
+	Strict:     true,
+	Properties: colorProperties(),
+}
+
+func colorProperties() []*config.PropertySpec {
+	var props []*config.PropertySpec
+	for _, p := range spec.Properties {
+		if strings.HasSuffix(p.Name, "-color") {
+			props = append(props, p)
+		}
+	}
+
+	return props
+}
+
+// themeFile returns path to the named theme file.
+func themeFile(name string) (string, error) {
+	cd, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cd, "themes", name+".conf"), nil
+}
+
+func parseTheme(v any) (any, error) {
+	name := v.(string)
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, escapeError(fmt.Errorf("invalid theme name: %s",
+			name))
+	}
+
+	return name, nil
+}
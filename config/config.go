@@ -6,11 +6,12 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	ncurses "github.com/gbin/goncurses"
-	"github.com/vchimishuk/asp/format"
 	"github.com/vchimishuk/config"
 )
 
@@ -20,37 +21,77 @@ var spec = &config.Spec{
 	Strict: true,
 	Properties: []*config.PropertySpec{
 		&config.PropertySpec{
-			Type: config.TypeString,
-			Name: "chub-host",
+			Type:   config.TypeString,
+			Name:   "chub-host",
+			Parser: parseAddr,
 		},
 		&config.PropertySpec{
 			Type: config.TypeInt,
 			Name: "chub-port",
 		},
+		&config.PropertySpec{
+			Type: config.TypeString,
+			Name: "server",
+		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "theme",
+			Parser: parseTheme,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   "bind",
+			Repeat: true,
+			Parser: parseBinding,
+		},
+		&config.PropertySpec{
+			Type: config.TypeDuration,
+			Name: "key-timeout",
+		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "clock-format",
+			Parser: parseClock,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "browser-dir-format",
-			Parser: parseFormat,
+			Parser: formatParser(DirVerbs),
 		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "browser-track-format",
-			Parser: parseFormat,
+			Parser: formatParser(TrackVerbs),
+		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "help-format",
+			Parser: formatParser(HelpVerbs),
+		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "playlist-track-format",
+			Parser: formatParser(TrackVerbs),
+		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "playlists-format",
+			Parser: formatParser(PlaylistsVerbs),
 		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "status-paused-format",
-			Parser: parseFormat,
+			Parser: formatParser(PlayerVerbs),
 		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "status-playing-format",
-			Parser: parseFormat,
+			Parser: formatParser(PlayerVerbs),
 		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "title-format",
-			Parser: parseFormat,
+			Parser: formatParser(PlayerVerbs),
 		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
@@ -87,6 +128,11 @@ var spec = &config.Spec{
 			Name:   "status-color",
 			Parser: parseColor,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeString,
+			Name:   "tagged-color",
+			Parser: parseColor,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeString,
 			Name:   "title-color",
@@ -102,6 +148,31 @@ var spec = &config.Spec{
 			Name:   string(CmdBack) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdClearTags) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdCommand) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdCopyPath) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdCreatePlaylist) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdDeletePlaylist) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdDown) + "-key",
@@ -112,16 +183,31 @@ var spec = &config.Spec{
 			Name:   string(CmdEnd) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdHelp) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdHome) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdInvertTags) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdKill) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdNext) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdPageDown) + "-key",
@@ -142,11 +228,36 @@ var spec = &config.Spec{
 			Name:   string(CmdPlay) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdPlaylist) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdPlaylists) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdPrev) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdQuit) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdReload) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdRenamePlaylist) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdSearch) + "-key",
@@ -172,6 +283,11 @@ var spec = &config.Spec{
 			Name:   string(CmdSeekForward) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdServer) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdShowActive) + "-key",
@@ -182,6 +298,21 @@ var spec = &config.Spec{
 			Name:   string(CmdStop) + "-key",
 			Parser: parseKey,
 		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdTag) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdTagPattern) + "-key",
+			Parser: parseKey,
+		},
+		&config.PropertySpec{
+			Type:   config.TypeStringList,
+			Name:   string(CmdTagRange) + "-key",
+			Parser: parseKey,
+		},
 		&config.PropertySpec{
 			Type:   config.TypeStringList,
 			Name:   string(CmdUp) + "-key",
@@ -198,22 +329,18 @@ var spec = &config.Spec{
 			Parser: parseKey,
 		},
 	},
-}
-
-var colorNames = map[string]int16{
-	"black":   ncurses.C_BLACK,
-	"blue":    ncurses.C_BLUE,
-	"cyan":    ncurses.C_CYAN,
-	"green":   ncurses.C_GREEN,
-	"magenta": ncurses.C_MAGENTA,
-	"red":     ncurses.C_RED,
-	"white":   ncurses.C_WHITE,
-	"yellow":  ncurses.C_YELLOW,
+	Blocks: []*config.BlockSpec{
+		serversSpec,
+	},
 }
 
 var (
 	ChubHost string
 	ChubPort int
+	// Time to wait for the next key of a key sequence.
+	KeyTimeout time.Duration
+	// Pattern of the clock and the track end time, e.g. "%H:%M".
+	ClockFormat string
 )
 
 var (
@@ -224,12 +351,16 @@ var (
 	ColorMessage      ncurses.Char
 	ColorNormal       ncurses.Char
 	ColorStatus       ncurses.Char
+	ColorTagged       ncurses.Char
 	ColorTitle        ncurses.Char
 )
 
 var (
 	FormatBrowserDir    string
 	FormatBrowserTrack  string
+	FormatHelp          string
+	FormatPlaylistTrack string
+	FormatPlaylists     string
 	FormatStatusPaused  string
 	FormatStatusPlaying string
 	FormatTitle         string
@@ -238,289 +369,507 @@ var (
 type Cmd string
 
 const (
-	CmdApply        Cmd = "apply"
-	CmdBack         Cmd = "back"
-	CmdDown         Cmd = "down"
-	CmdEnd          Cmd = "end"
-	CmdHome         Cmd = "home"
-	CmdKill         Cmd = "kill"
-	CmdNoop         Cmd = "noop"
-	CmdPageDown     Cmd = "page-down"
-	CmdPageUp       Cmd = "page-up"
-	CmdPause        Cmd = "pause"
-	CmdPlay         Cmd = "play"
-	CmdQuit         Cmd = "quit"
-	CmdSearch       Cmd = "search"
-	CmdSearchNext   Cmd = "search-next"
-	CmdSearchPrev   Cmd = "search-prev"
-	CmdSeekBackward Cmd = "seek-backward"
-	CmdSeekForward  Cmd = "seek-forward"
-	CmdShowActive   Cmd = "show-active"
-	CmdStop         Cmd = "stop"
-	CmdUp           Cmd = "up"
-	CmdVolumeDown   Cmd = "volume-down"
-	CmdVolumeUp     Cmd = "volume-up"
+	CmdApply          Cmd = "apply"
+	CmdBack           Cmd = "back"
+	CmdBind           Cmd = "bind"
+	CmdCd             Cmd = "cd"
+	CmdClearTags      Cmd = "clear-tags"
+	CmdCommand        Cmd = "command"
+	CmdCopyPath       Cmd = "copy-path"
+	CmdCreatePlaylist Cmd = "create-playlist"
+	CmdDeletePlaylist Cmd = "delete-playlist"
+	CmdDown           Cmd = "down"
+	CmdEnd            Cmd = "end"
+	CmdHelp           Cmd = "help"
+	CmdHome           Cmd = "home"
+	CmdInvertTags     Cmd = "invert-tags"
+	CmdKill           Cmd = "kill"
+	CmdNext           Cmd = "next"
+	CmdNoop           Cmd = "noop"
+	CmdPageDown       Cmd = "page-down"
+	CmdPageUp         Cmd = "page-up"
+	CmdPause          Cmd = "pause"
+	CmdPlay           Cmd = "play"
+	CmdPlaylist       Cmd = "playlist"
+	CmdPlaylists      Cmd = "playlists"
+	CmdPrev           Cmd = "prev"
+	CmdQuit           Cmd = "quit"
+	CmdReload         Cmd = "reload"
+	CmdRenamePlaylist Cmd = "rename-playlist"
+	CmdSearch         Cmd = "search"
+	CmdSearchNext     Cmd = "search-next"
+	CmdSearchPrev     Cmd = "search-prev"
+	CmdSeek           Cmd = "seek"
+	CmdSeekBackward   Cmd = "seek-backward"
+	CmdSeekForward    Cmd = "seek-forward"
+	CmdServer         Cmd = "server"
+	CmdShowActive     Cmd = "show-active"
+	CmdStop           Cmd = "stop"
+	CmdTag            Cmd = "tag"
+	CmdTagPattern     Cmd = "tag-pattern"
+	CmdTagRange       Cmd = "tag-range"
+	CmdUp             Cmd = "up"
+	CmdVolume         Cmd = "volume"
+	CmdVolumeDown     Cmd = "volume-down"
+	CmdVolumeUp       Cmd = "volume-up"
 )
 
-var defKeymap = map[Cmd][]ncurses.Key{
-	CmdApply: []ncurses.Key{
-		ncurses.KEY_RETURN,
-		ncurses.Key('l'),
+var defKeymap = map[Cmd][]KeySeq{
+	CmdApply: []KeySeq{
+		{ncurses.KEY_RETURN},
+		{ncurses.Key('l')},
+	},
+	CmdBack: []KeySeq{
+		{ncurses.KEY_BACKSPACE},
+		{ncurses.Key('h')},
+		{ctrlKey('h')},
+	},
+	CmdClearTags: []KeySeq{
+		{ncurses.Key('-')},
+	},
+	CmdCommand: []KeySeq{
+		{ncurses.Key(':')},
+	},
+	CmdCopyPath: []KeySeq{
+		{ncurses.Key('y')},
 	},
-	CmdBack: []ncurses.Key{
-		ncurses.KEY_BACKSPACE,
-		ncurses.Key('h'),
-		ctrlKey('h'),
+	CmdCreatePlaylist: []KeySeq{
+		{ncurses.Key('c')},
 	},
-	CmdDown: []ncurses.Key{
-		ncurses.KEY_DOWN,
-		ncurses.Key('j'),
-		ctrlKey('n'),
+	CmdDeletePlaylist: []KeySeq{
+		{ncurses.Key('D')},
 	},
-	CmdEnd: []ncurses.Key{
-		ncurses.KEY_END,
-		ctrlKey('e'),
+	CmdDown: []KeySeq{
+		{ncurses.KEY_DOWN},
+		{ncurses.Key('j')},
+		{ctrlKey('n')},
 	},
-	CmdHome: []ncurses.Key{
-		ncurses.KEY_HOME,
-		ctrlKey('a'),
+	CmdEnd: []KeySeq{
+		{ncurses.KEY_END},
+		{ctrlKey('e')},
+		{ncurses.Key('G')},
 	},
-	CmdKill: []ncurses.Key{
-		ncurses.Key('K'),
+	CmdHelp: []KeySeq{
+		{ncurses.Key('?')},
+		{ncurses.KEY_F1},
 	},
-	CmdPageDown: []ncurses.Key{
-		ncurses.KEY_PAGEDOWN,
-		ctrlKey('v'),
-		ctrlKey('d'),
+	CmdHome: []KeySeq{
+		{ncurses.KEY_HOME},
+		{ctrlKey('a')},
+		{ncurses.Key('g'), ncurses.Key('g')},
 	},
-	CmdPageUp: []ncurses.Key{
-		ncurses.KEY_PAGEUP,
-		ctrlKey('b'),
+	CmdInvertTags: []KeySeq{
+		{ncurses.Key('i')},
 	},
-	CmdPause: []ncurses.Key{
-		ncurses.Key(' '),
-		ncurses.Key('p'),
+	CmdKill: []KeySeq{
+		{ncurses.Key('K')},
 	},
-	CmdPlay: []ncurses.Key{
-		ncurses.Key('x'),
+	CmdNext: []KeySeq{
+		{ncurses.Key('>')},
 	},
-	CmdQuit: []ncurses.Key{
-		ncurses.Key('q'),
+	CmdPageDown: []KeySeq{
+		{ncurses.KEY_PAGEDOWN},
+		{ctrlKey('v')},
+		{ctrlKey('d')},
 	},
-	CmdSearch: []ncurses.Key{
-		ncurses.Key('/'),
+	CmdPageUp: []KeySeq{
+		{ncurses.KEY_PAGEUP},
+		{ctrlKey('b')},
 	},
-	CmdSearchNext: []ncurses.Key{
-		ncurses.Key('n'),
+	CmdPause: []KeySeq{
+		{ncurses.Key(' ')},
+		{ncurses.Key('p')},
 	},
-	CmdSearchPrev: []ncurses.Key{
-		ncurses.Key('N'),
+	CmdPlay: []KeySeq{
+		{ncurses.Key('x')},
 	},
-	CmdSeekBackward: []ncurses.Key{
-		ncurses.Key(','),
-		ncurses.KEY_LEFT,
+	CmdPlaylist: []KeySeq{
+		{ncurses.Key('\t')},
+		{ncurses.Key('g'), ncurses.Key('p')},
 	},
-	CmdSeekForward: []ncurses.Key{
-		ncurses.Key('.'),
-		ncurses.KEY_RIGHT,
+	CmdPlaylists: []KeySeq{
+		{ncurses.Key('P')},
 	},
-	CmdShowActive: []ncurses.Key{
-		ncurses.Key('a'),
+	CmdPrev: []KeySeq{
+		{ncurses.Key('<')},
 	},
-	CmdStop: []ncurses.Key{
-		ncurses.Key('s'),
+	CmdQuit: []KeySeq{
+		{ncurses.Key('q')},
 	},
-	CmdUp: []ncurses.Key{
-		ncurses.KEY_UP,
-		ncurses.Key('k'),
-		ctrlKey('p'),
+	CmdReload: []KeySeq{
+		{ctrlKey('r')},
 	},
-	CmdVolumeDown: []ncurses.Key{
-		ncurses.Key('9'),
+	CmdRenamePlaylist: []KeySeq{
+		{ncurses.Key('r')},
 	},
-	CmdVolumeUp: []ncurses.Key{
-		ncurses.Key('0'),
+	CmdSearch: []KeySeq{
+		{ncurses.Key('/')},
+	},
+	CmdSearchNext: []KeySeq{
+		{ncurses.Key('n')},
+	},
+	CmdSearchPrev: []KeySeq{
+		{ncurses.Key('N')},
+	},
+	CmdSeekBackward: []KeySeq{
+		{ncurses.Key(',')},
+		{ncurses.KEY_LEFT},
+	},
+	CmdSeekForward: []KeySeq{
+		{ncurses.Key('.')},
+		{ncurses.KEY_RIGHT},
+	},
+	CmdServer: []KeySeq{
+		{ncurses.Key('S')},
+	},
+	CmdShowActive: []KeySeq{
+		{ncurses.Key('a')},
+	},
+	CmdStop: []KeySeq{
+		{ncurses.Key('s')},
+	},
+	CmdTag: []KeySeq{
+		{ncurses.Key('t')},
+	},
+	CmdTagPattern: []KeySeq{
+		{ncurses.Key('+')},
+	},
+	CmdTagRange: []KeySeq{
+		{ncurses.Key('T')},
+	},
+	CmdUp: []KeySeq{
+		{ncurses.KEY_UP},
+		{ncurses.Key('k')},
+		{ctrlKey('p')},
+	},
+	CmdVolumeDown: []KeySeq{
+		{ncurses.Key('9')},
+	},
+	CmdVolumeUp: []KeySeq{
+		{ncurses.Key('0')},
 	},
 }
 
-var keymap map[ncurses.Key]Cmd = make(map[ncurses.Key]Cmd)
+// Commands which require arguments, so they can be bound only with bind
+// property and do not have default keys.
+var argCmds = []Cmd{
+	CmdBind,
+	CmdCd,
+	CmdSeek,
+	CmdVolume,
+}
 
-func Load() error {
-	var cfg *config.Config
-	cd, err := configDir()
-	if err != nil {
-		return err
-	}
-	file := filepath.Join(cd, "asp.conf")
-	d, err := os.ReadFile(file)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			cfg = &config.Config{}
-		} else {
-			return err
-		}
-	} else {
-		cfg, err = config.Parse(spec, string(d))
-		if err != nil {
-			return err
-		}
+// defColors lists color pair properties with pair IDs and default values.
+var defColors = []struct {
+	ID   int16
+	Var  *ncurses.Char
+	Name string
+	Def  string
+}{
+	{1, &ColorCursor, "cursor-color", "black:cyan"},
+	{2, &ColorCursorActive, "cursor-active-color", "red:cyan"},
+	{3, &ColorList, "list-color", "white:black"},
+	{4, &ColorListActive, "list-active-color", "red:black"},
+	{5, &ColorMessage, "message-color", "red:black"},
+	{6, &ColorNormal, "normal-color", "white:black"},
+	{7, &ColorStatus, "status-color", "black:blue"},
+	{8, &ColorTitle, "title-color", "black:blue"},
+	{9, &ColorTagged, "tagged-color", "yellow:black"},
+}
 
-	}
+// Default values of the scalar properties.
+const (
+	defChubHost    = "localhost"
+	defKeyTimeout  = time.Second
+	defClockFormat = "%H:%M"
+)
 
-	ChubHost = cfg.StringOr("chub-host", "localhost")
-	ChubPort = cfg.IntOr("chub-port", DefaultPort)
+// keymap maps key sequence id to the bound action.
+var keymap map[string]Action = make(map[string]Action)
+
+// keyPrefixes contains ids of all key sequences which are prefixes of
+// bound sequences, so more keys are expected to complete the sequence.
+var keyPrefixes map[string]bool = make(map[string]bool)
+
+// Binding binds key sequence to the action.
+type Binding struct {
+	Keys   KeySeq
+	Action Action
+}
 
-	err = initColors(cfg)
+// Load reads configuration file and applies it. Ncurses must be
+// initialized before the call, since color pairs are set up here.
+func Load() error {
+	cfg, theme, err := parse()
 	if err != nil {
 		return err
 	}
-	err = initKeymap(cfg)
+	servers, defServer, err := loadServers(cfg)
 	if err != nil {
 		return err
 	}
-	err = initFormats(cfg)
+	// Colors are the only part which can fail to apply. Previous
+	// colors are restored on failure, so failed reload does not
+	// change anything.
+	err = initColors(cfg, theme)
 	if err != nil {
 		return err
 	}
 
+	ChubHost = cfg.StringOr("chub-host", defChubHost)
+	ChubPort = cfg.IntOr("chub-port", DefaultPort)
+	Servers = servers
+	DefaultServer = defServer
+	KeyTimeout = cfg.DurationOr("key-timeout", defKeyTimeout)
+	ClockFormat = cfg.StringOr("clock-format", defClockFormat)
+	initKeymap(cfg)
+	initFormats(cfg)
+
 	return nil
 }
 
-func Command(key ncurses.Key) Cmd {
-	c, ok := keymap[key]
-	if ok {
-		return c
+// Check parses configuration file and validates all its properties
+// without applying them. Ncurses is not required.
+func Check() error {
+	cfg, _, err := parse()
+	if err == nil {
+		_, _, err = loadServers(cfg)
 	}
 
-	return CmdNoop
+	return err
 }
 
-func initFormats(cfg *config.Config) error {
-	fmts := []struct {
-		Name string
-		Var  *string
-		Def  string
-	}{
-		{"browser-dir-format", &FormatBrowserDir,
-			"{%n}/"},
-		{"browser-track-format", &FormatBrowserTrack,
-			"{-*%:%a - %t}{20%:%l}"},
-		{"status-paused-format", &FormatStatusPaused,
-			"{-*%:%a - %t}{*%:[%o/%l]}"},
-		{"status-playing-format", &FormatStatusPlaying,
-			"{-*%:%a - %t}{*%:[%o/%l]}"},
-		{"title-format", &FormatTitle,
-			"{-*%:%p}{*%:[%v%%]}"},
+// File returns path to the configuration file.
+func File() (string, error) {
+	cd, err := configDir()
+	if err != nil {
+		return "", err
 	}
 
-	for _, f := range fmts {
-		*f.Var = cfg.StringOr(f.Name, f.Def)
+	return filepath.Join(cd, "asp.conf"), nil
+}
+
+// parse reads and parses configuration file and the theme file it
+// refers to. Absent configuration file is treated as an empty one.
+// Errors are reported in file:line: message form.
+func parse() (cfg *config.Config, theme *config.Config, err error) {
+	file, err := File()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err = parseFile(spec, file)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg = &config.Config{}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	theme = &config.Config{}
+	if name := cfg.StringOr("theme", ""); name != "" {
+		tf, err := themeFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		theme, err = parseFile(themeSpec, tf)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("theme not found: %s", name)
+		} else if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return nil
+	return cfg, theme, nil
 }
 
-func initKeymap(cfg *config.Config) error {
-	for cmd, keys := range defKeymap {
-		ks := cfg.AnyOr(string(cmd)+"-key", keys).([]ncurses.Key)
-		for _, k := range ks {
-			keymap[k] = cmd
-		}
+func parseFile(s *config.Spec, file string) (*config.Config, error) {
+	d, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseText(s, string(d))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", file, err)
 	}
 
-	return nil
+	return cfg, nil
 }
 
-func initColors(cfg *config.Config) error {
-	colors := []struct {
-		ID   int16
-		Var  *ncurses.Char
-		Name string
-		Def  []int16
-	}{
-		{1, &ColorCursor, "cursor-color",
-			[]int16{colorNames["black"],
-				colorNames["cyan"]}},
-		{2, &ColorCursorActive, "cursor-active-color",
-			[]int16{colorNames["red"],
-				colorNames["cyan"]}},
-		{3, &ColorList, "list-color",
-			[]int16{colorNames["white"],
-				colorNames["black"]}},
-		{4, &ColorListActive, "list-active-color",
-			[]int16{colorNames["red"],
-				colorNames["black"]}},
-		{5, &ColorMessage, "message-color",
-			[]int16{colorNames["red"],
-				colorNames["black"]}},
-		{6, &ColorNormal, "normal-color",
-			[]int16{colorNames["white"],
-				colorNames["black"]}},
-		{7, &ColorStatus, "status-color",
-			[]int16{colorNames["black"],
-				colorNames["blue"]}},
-		{8, &ColorTitle, "title-color",
-			[]int16{colorNames["black"],
-				colorNames["blue"]}},
+// parseText parses configuration text. Errors are reported in
+// line: message form.
+func parseText(s *config.Spec, text string) (*config.Config, error) {
+	failedList = nil
+	cfg, err := config.Parse(s, text)
+	if err == nil || failedList == nil {
+		return cfg, err
+	}
+
+	// Config library reports list property parser errors at the line
+	// of the token following the list, which can be the next property.
+	l, msg, _ := strings.Cut(err.Error(), ": ")
+	line, cerr := strconv.Atoi(l)
+	if cerr != nil {
+		return nil, err
 	}
 
-	for _, c := range colors {
-		v := cfg.AnyOr(c.Name, c.Def)
-		fg := v.([]int16)[0]
-		bg := v.([]int16)[1]
-		err := ncurses.InitPair(c.ID, fg, bg)
+	return nil, fmt.Errorf("%d: %s", listLine(text, failedList, line), msg)
+}
+
+// listLine returns the line of the last value of the list lst which is
+// followed by the token at the given line or by the end of the text.
+func listLine(text string, lst []string, line int) int {
+	last := lst[len(lst)-1]
+	t := config.NewTokenizer(text)
+	// Line of the last string token equal to the last list value.
+	valueLine := 0
+	for t.HasNext() {
+		tk, err := t.Next()
 		if err != nil {
-			return err
+			break
+		}
+		if valueLine > 0 && tk.Name != config.NameComma &&
+			t.Line() == line {
+
+			return valueLine
+		}
+		valueLine = 0
+		if tk.Name == config.NameString && tk.Value == last {
+			valueLine = t.Line()
 		}
-		*c.Var = ncurses.ColorPair(c.ID)
+	}
+	if valueLine > 0 {
+		return valueLine
 	}
 
-	return nil
+	return line
 }
 
-func parseFormat(v any) (any, error) {
-	return v, format.Validate(v.(string))
+// KeyAction returns action bound to the given key sequence. CmdNoop
+// action is returned if nothing is bound. prefix is true if the sequence
+// is a beginning of some longer bound sequence.
+func KeyAction(keys KeySeq) (action Action, prefix bool) {
+	a, ok := keymap[keys.id()]
+	if !ok {
+		a = Action{Cmd: CmdNoop}
+	}
+
+	return a, keyPrefixes[keys.id()]
 }
 
-func parseColor(v any) (any, error) {
-	pts := strings.SplitN(v.(string), ":", 2)
-	if len(pts) != 2 {
-		return 0, errors.New("invalid color pair")
+// Commands returns names of all available commands.
+func Commands() []Cmd {
+	cmds := make([]Cmd, 0, len(defKeymap)+len(argCmds))
+	for c := range defKeymap {
+		cmds = append(cmds, c)
 	}
-	fg, ok := colorNames[pts[0]]
-	if !ok {
-		return 0, fmt.Errorf("invalid color: %s", pts[0])
+	cmds = append(cmds, argCmds...)
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i] < cmds[j]
+	})
+
+	return cmds
+}
+
+// ParseCmd returns command by its name.
+func ParseCmd(s string) (Cmd, error) {
+	c := Cmd(s)
+	if _, ok := defKeymap[c]; ok {
+		return c, nil
 	}
-	bg, ok := colorNames[pts[1]]
-	if !ok {
-		return 0, fmt.Errorf("invalid color: %s", pts[1])
+	for _, ac := range argCmds {
+		if ac == c {
+			return c, nil
+		}
 	}
 
-	return []int16{fg, bg}, nil
+	return CmdNoop, fmt.Errorf("invalid command: %s", s)
+}
+
+// Bind binds key sequence to the action replacing previous binding
+// if any.
+func Bind(keys KeySeq, action Action) {
+	keymap[keys.id()] = action
+	for i := 1; i < len(keys); i++ {
+		keyPrefixes[keys[:i].id()] = true
+	}
 }
 
+func initKeymap(cfg *config.Config) {
+	// Start from scratch so keys removed from the configuration file
+	// become unbound on reload.
+	keymap = make(map[string]Action)
+	keyPrefixes = make(map[string]bool)
+	for cmd, keys := range defKeymap {
+		ks := cfg.AnyOr(string(cmd)+"-key", keys).([]KeySeq)
+		for _, k := range ks {
+			Bind(k, Action{Cmd: cmd})
+		}
+	}
+	// Explicit bindings override any other ones.
+	for _, p := range cfg.Properties {
+		if p.Name == "bind" {
+			b := p.Value.(Binding)
+			Bind(b.Keys, b.Action)
+		}
+	}
+}
+
+// parseKey parses list of key sequences. Every sequence consists of
+// one or more keys. Key is a single char, ^X for control chars or
+// #code for raw ncurses key codes, e.g. "gg", "^d", "#265".
 func parseKey(v any) (any, error) {
-	res := []ncurses.Key{}
+	res := []KeySeq{}
 
 	for _, s := range v.([]string) {
-		if len(s) == 1 {
-			res = append(res, ncurses.Key(rune(s[0])))
-		} else if len(s) == 2 && s[0] == '^' {
-			res = append(res, ctrlKey(rune(s[1])))
-		} else if len(s) > 1 && s[0] == '#' {
-			i, err := strconv.Atoi(s[1:])
-			if err != nil {
-				return nil, err
-			}
-			res = append(res, ncurses.Key(i))
-		} else {
-			return nil, fmt.Errorf("invalid key: %s", s)
+		seq, err := ParseKey(s)
+		if err != nil {
+			return nil, listError(v.([]string), err)
 		}
+		res = append(res, seq)
 	}
 
 	return res, nil
 }
 
+// parseBinding parses bind property which binds key to the command with
+// arguments, e.g. bind = "L", "seek-forward 60".
+func parseBinding(v any) (any, error) {
+	lst := v.([]string)
+	if len(lst) != 2 {
+		return nil, listError(lst,
+			errors.New("key and command expected"))
+	}
+	k, err := ParseKey(lst[0])
+	if err != nil {
+		return nil, listError(lst, err)
+	}
+	a, err := ParseAction(lst[1])
+	if err != nil {
+		return nil, listError(lst, err)
+	}
+
+	return Binding{Keys: k, Action: a}, nil
+}
+
+// failedList is the value of the list property parser failed on.
+var failedList []string
+
+// listError remembers the failed list property value, so parseText
+// can find the line the property is at.
+func listError(lst []string, err error) error {
+	failedList = lst
+
+	return escapeError(err)
+}
+
+// escapeError escapes % in the property parser error, because
+// the config library treats the error message as a format string.
+func escapeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return errors.New(strings.ReplaceAll(err.Error(), "%", "%%"))
+}
+
 func ctrlKey(r rune) ncurses.Key {
 	return ncurses.Key(r) & 0x1F
 
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	ncurses "github.com/gbin/goncurses"
+	"github.com/vchimishuk/config"
+)
+
+func testParseKey(t *testing.T, s string, expected KeySeq) {
+	actual, err := ParseKey(s)
+	if err != nil {
+		t.Errorf("Error parsing key \"%s\". %s", s, err)
+	} else if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Key parse error.\nExpected: %v\nActual: %v",
+			expected, actual)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	testParseKey(t, "x", KeySeq{'x'})
+	testParseKey(t, "^", KeySeq{'^'})
+	testParseKey(t, "^d", KeySeq{0x04})
+	testParseKey(t, "#265", KeySeq{265})
+	testParseKey(t, "gg", KeySeq{'g', 'g'})
+	testParseKey(t, "g^d#265", KeySeq{'g', 0x04, 265})
+	testParseKey(t, "F1", KeySeq{ncurses.KEY_F1})
+	testParseKey(t, "pageup", KeySeq{ncurses.KEY_PAGEUP})
+	testParseKey(t, "M-x", KeySeq{0x1B, 'x'})
+	testParseKey(t, "g<Left><M-Space>", KeySeq{'g', ncurses.KEY_LEFT,
+		0x1B, ' '})
+	testParseKey(t, "<", KeySeq{'<'})
+	testParseKey(t, "<>", KeySeq{'<', '>'})
+
+	if _, err := ParseKey(""); err == nil {
+		t.Errorf("Error expected parsing empty key.")
+	}
+	if _, err := ParseKey("<Foo>"); err == nil {
+		t.Errorf("Error expected parsing unknown key name.")
+	}
+}
+
+func TestKeyAction(t *testing.T) {
+	Bind(KeySeq{'z', 'z'}, Action{Cmd: CmdStop})
+
+	a, prefix := KeyAction(KeySeq{'z'})
+	if a.Cmd != CmdNoop || !prefix {
+		t.Errorf("Prefix expected, got %s, %t", a, prefix)
+	}
+	a, prefix = KeyAction(KeySeq{'z', 'z'})
+	if a.Cmd != CmdStop || prefix {
+		t.Errorf("Stop expected, got %s, %t", a, prefix)
+	}
+	if s := (KeySeq{'z', ncurses.Key(0x04)}).String(); s != "z^d" {
+		t.Errorf("Key sequence name error: %s", s)
+	}
+	if s := (KeySeq{ncurses.KEY_F1, 0x1B, 'x'}).String(); s != "<F1><M-x>" {
+		t.Errorf("Key sequence name error: %s", s)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var lines []string
+	prop := regexp.MustCompile(`^# [a-z-]+ = `)
+	for _, l := range strings.Split(Default(), "\n") {
+		if prop.MatchString(l) {
+			lines = append(lines, strings.TrimPrefix(l, "# "))
+		}
+	}
+	cfg, err := config.Parse(spec, strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatalf("Default configuration parse error: %s", err)
+	}
+	for cmd, keys := range defKeymap {
+		actual := cfg.Any(string(cmd) + "-key")
+		if !reflect.DeepEqual(keys, actual) {
+			t.Errorf("Default %s keys mismatch.\nExpected: %v\n"+
+				"Actual: %v", cmd, keys, actual)
+		}
+	}
+}
+
+func TestParseColorSpec(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected colorSpec
+	}{
+		{"red:black", colorSpec{fg: color{index: ncurses.C_RED},
+			bg: color{index: ncurses.C_BLACK}}},
+		{"default:236:bold,underline", colorSpec{
+			fg:    color{index: colorDefault},
+			bg:    color{index: 236},
+			attrs: ncurses.A_BOLD | ncurses.A_UNDERLINE}},
+		{"#ff8000:white", colorSpec{
+			fg: color{rgb: true, r: 0xff, g: 0x80, b: 0x00},
+			bg: color{index: ncurses.C_WHITE}}},
+	}
+	for _, test := range tests {
+		actual, err := parseColorSpec(test.s)
+		if err != nil {
+			t.Errorf("Error parsing color \"%s\". %s", test.s, err)
+		} else if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("Color parse error.\nExpected: %v\nActual: %v",
+				test.expected, actual)
+		}
+	}
+
+	for _, s := range []string{"red", "red:256", "red:#fff",
+		"red:black:italic", "foo:black", "red:black:bold:dim"} {
+		if _, err := parseColorSpec(s); err == nil {
+			t.Errorf("Error expected parsing color \"%s\".", s)
+		}
+	}
+}
+
+func TestColorPalette(t *testing.T) {
+	tests := []struct {
+		c    color
+		p256 int16
+		p8   int16
+	}{
+		{color{rgb: true, r: 0, g: 0, b: 0}, 16, ncurses.C_BLACK},
+		{color{rgb: true, r: 255, g: 0, b: 0}, 196, ncurses.C_RED},
+		{color{rgb: true, r: 0x70, g: 0x70, b: 0x70}, 242,
+			ncurses.C_BLACK},
+		{color{rgb: true, r: 0xff, g: 0xff, b: 0xd7}, 230,
+			ncurses.C_WHITE},
+		// Palette indexes fall back to basic colors as well.
+		{indexColor(236), 236, ncurses.C_BLACK},
+		{indexColor(196), 196, ncurses.C_RED},
+		{indexColor(231), 231, ncurses.C_WHITE},
+		{indexColor(30), 30, ncurses.C_CYAN},
+		{indexColor(45), 45, ncurses.C_CYAN},
+	}
+	for _, test := range tests {
+		if p := test.c.palette256(); p != test.p256 {
+			t.Errorf("256-color palette index of %v expected %d, got %d",
+				test.c, test.p256, p)
+		}
+		if p := test.c.palette8(); p != test.p8 {
+			t.Errorf("8-color palette index of %v expected %d, got %d",
+				test.c, test.p8, p)
+		}
+	}
+}
+
+func TestLoadServers(t *testing.T) {
+	cfg, err := config.Parse(spec, `
+chub-host = "example.com"
+server = "kitchen"
+servers {
+    kitchen {
+        host = "kitchen.local"
+        path = "/music"
+    }
+    hall {
+        port = 6000
+    }
+}`)
+	if err != nil {
+		t.Fatalf("Configuration parse error: %s", err)
+	}
+	servers, def, err := loadServers(cfg)
+	if err != nil {
+		t.Fatalf("Servers load error: %s", err)
+	}
+	expected := []*Server{
+		{Name: "kitchen", Host: "kitchen.local", Port: DefaultPort,
+			Path: "/music"},
+		{Name: "hall", Host: "example.com", Port: 6000, Path: "/"},
+	}
+	if !reflect.DeepEqual(expected, servers) {
+		t.Errorf("Servers mismatch.\nExpected: %v\nActual: %v",
+			expected, servers)
+	}
+	if def != "kitchen" {
+		t.Errorf("Default server expected kitchen, got %s", def)
+	}
+
+	for _, s := range []string{
+		`server = "hall"`,
+		`servers { ../x { host = "h" } }`,
+		`servers { hall { name = "h" } }`,
+	} {
+		cfg, err := config.Parse(spec, s)
+		if err == nil {
+			_, _, err = loadServers(cfg)
+		}
+		if err == nil {
+			t.Errorf("Error expected loading servers \"%s\".", s)
+		}
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Addr
+	}{
+		{"localhost", Addr{Host: "localhost", Port: 5115}},
+		{"localhost:6000", Addr{Host: "localhost", Port: 6000}},
+		{"::1", Addr{Host: "::1", Port: 5115}},
+		{"[::1]", Addr{Host: "::1", Port: 5115}},
+		{"[::1]:6000", Addr{Host: "::1", Port: 6000}},
+		{"unix:/run/chub.sock", Addr{Path: "/run/chub.sock"}},
+	}
+	for _, test := range tests {
+		actual, err := ParseAddr(test.s, 5115)
+		if err != nil {
+			t.Errorf("Error parsing address \"%s\". %s", test.s, err)
+		} else if test.expected != actual {
+			t.Errorf("Address parse error.\nExpected: %v\nActual: %v",
+				test.expected, actual)
+		}
+	}
+
+	for _, s := range []string{"", "unix:", ":6000", "localhost:",
+		"localhost:port", "[::1]:70000"} {
+		if _, err := ParseAddr(s, 5115); err == nil {
+			t.Errorf("Error expected parsing address \"%s\".", s)
+		}
+	}
+}
+
+func TestParseErrorPercent(t *testing.T) {
+	tests := []struct {
+		s   string
+		msg string
+	}{
+		{`browser-dir-format = "{%{foo}}"`, "%{foo}"},
+		{`list-color = "red%:black"`, "red%"},
+	}
+	for _, test := range tests {
+		_, err := config.Parse(spec, test.s)
+		if err == nil {
+			t.Errorf("Error expected parsing \"%s\".", test.s)
+		} else if !strings.Contains(err.Error(), test.msg) ||
+			strings.Contains(err.Error(), "%!") {
+			t.Errorf("Error message for \"%s\" expected to contain "+
+				"\"%s\", got: %s", test.s, test.msg, err)
+		}
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	tests := []struct {
+		s    string
+		line string
+	}{
+		{"bind = \"L\", \"foo 60\"\nnext-key = \"n\"", "1: "},
+		{"bind = \"L\", \"foo 60\"\n\n# comment\nnext-key = \"n\"", "1: "},
+		{"next-key = \"n\"\nbind = \"L\",\n  \"foo 60\"\n", "3: "},
+		{"next-key = \"\"\n\nprev-key = \"p\"", "1: "},
+		{"bind = \"L\"\nnext-key = \"n\"", "1: "},
+		{"next-key = \"n\"\nlist-color = \"zz\"\n", "2: "},
+	}
+	for _, test := range tests {
+		_, err := parseText(spec, test.s)
+		if err == nil {
+			t.Errorf("Error expected parsing \"%s\".", test.s)
+		} else if !strings.HasPrefix(err.Error(), test.line) {
+			t.Errorf("Error for \"%s\" expected at line %s got: %s",
+				test.s, test.line, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default returns configuration file with all properties set to their
+// default values. All properties are commented out, so the output can
+// be used as a starting point for the user's own configuration.
+func Default() string {
+	var b strings.Builder
+
+	b.WriteString("# Asp configuration file.\n")
+	b.WriteString("# All properties below are set to their default values.\n")
+	b.WriteString("\n# Chub server connection. Host can also be given " +
+		"as HOST:PORT,\n# [IPV6]:PORT or unix:PATH address.\n")
+	fmt.Fprintf(&b, "# chub-host = %s\n", quote(defChubHost))
+	fmt.Fprintf(&b, "# chub-port = %d\n", DefaultPort)
+
+	b.WriteString("\n# Named servers to switch between with server " +
+		"command or to connect\n# to with --server option. Host and " +
+		"port default to chub-host and\n# chub-port, path is a " +
+		"directory to start from if there is no last\n# path saved " +
+		"for the server. Server property sets the one to connect\n" +
+		"# to by default, e.g. server = \"kitchen\".\n")
+	b.WriteString("# servers {\n" +
+		"#     kitchen {\n" +
+		"#         host = \"kitchen.local\"\n" +
+		"#         port = 5115\n" +
+		"#         path = \"/\"\n" +
+		"#     }\n" +
+		"# }\n")
+
+	b.WriteString("\n# Time to wait for the next key of a key sequence.\n")
+	fmt.Fprintf(&b, "# key-timeout = %s\n", defKeyTimeout)
+
+	b.WriteString("\n# Pattern of %{clock} and %{end} verbs, strftime " +
+		"verbs are supported:\n# %a %A %b %B %d %e %H %I %j %m %M %p " +
+		"%S %y %Y %Z %%.\n")
+	fmt.Fprintf(&b, "# clock-format = %s\n", quote(defClockFormat))
+
+	b.WriteString("\n# Formats. Text can be styled with {$attr,...,color} " +
+		"markup, e.g.\n# {$bold,cyan}, {$reset} returns to the " +
+		"default style.\n")
+	for _, f := range defFormats {
+		fmt.Fprintf(&b, "# Verbs: %s.\n", f.Ctx)
+		fmt.Fprintf(&b, "# %s = %s\n", f.Name, quote(f.Def))
+	}
+
+	b.WriteString("\n# Colors in foreground:background[:attributes] form. " +
+		"Color is a basic\n# color name, default, 256-color palette " +
+		"index or #rrggbb value.\n# Attributes are comma separated: " +
+		"blink, bold, dim, reverse,\n# standout, underline. Colors can " +
+		"also be loaded from themes/NAME.conf\n# file in the " +
+		"configuration directory with theme = \"NAME\" property.\n")
+	for _, c := range defColors {
+		fmt.Fprintf(&b, "# %s = %s\n", c.Name, quote(c.Def))
+	}
+
+	b.WriteString("\n# Key bindings. Use bind property to bind a key " +
+		"to a command with\n# arguments, e.g. bind = \"L\", " +
+		"\"seek-forward 60\".\n")
+	var cmds []string
+	for cmd := range defKeymap {
+		cmds = append(cmds, string(cmd))
+	}
+	sort.Strings(cmds)
+	for _, cmd := range cmds {
+		var keys []string
+		for _, k := range defKeymap[Cmd(cmd)] {
+			keys = append(keys, quote(k.String()))
+		}
+		fmt.Fprintf(&b, "# %s-key = %s\n", cmd, strings.Join(keys, ", "))
+	}
+
+	return b.String()
+}
+
+// quote returns s as a configuration file string literal.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	return `"` + r.Replace(s) + `"`
+}
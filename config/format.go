@@ -0,0 +1,104 @@
+package config
+
+import (
+	"github.com/vchimishuk/asp/format"
+	"github.com/vchimishuk/config"
+)
+
+// Verbs available in the formats. Every context matches data the
+// corresponding window passes to its formatter.
+var (
+	// DirVerbs are used by browser directory format.
+	DirVerbs = format.Context{
+		{Short: 'n', Name: "name"},
+		{Short: 'p', Name: "path"},
+	}
+	// TrackVerbs are used by browser and playlist track formats.
+	TrackVerbs = format.Context{
+		{Short: 'a', Name: "artist"},
+		{Short: 'b', Name: "album"},
+		{Short: 't', Name: "title"},
+		{Short: 'n', Name: "track"},
+		{Short: 'l', Name: "length"},
+		{Short: 'p', Name: "path"},
+		{Short: 'f', Name: "filename"},
+	}
+	// PlayerVerbs are used by title and status formats and describe
+	// the playing track and the player state.
+	PlayerVerbs = format.Context{
+		{Short: 'a', Name: "artist"},
+		{Short: 'b', Name: "album"},
+		{Short: 't', Name: "title"},
+		{Short: 'n', Name: "track"},
+		{Short: 'l', Name: "length"},
+		{Short: 'o', Name: "elapsed"},
+		{Name: "remaining"},
+		{Name: "path"},
+		{Short: 'f', Name: "filename"},
+		{Short: 'r', Name: "tracks"},
+		{Short: 'q', Name: "position"},
+		{Short: 'v', Name: "volume"},
+		{Short: 'p', Name: "dir"},
+		{Name: "bar", Bar: true},
+		{Name: "volume-bar", Bar: true},
+		{Name: "percent"},
+		{Name: "clock"},
+		{Name: "end"},
+		{Name: "dir-length"},
+		{Name: "dir-tracks"},
+	}
+	// PlaylistsVerbs are used by playlists format.
+	PlaylistsVerbs = format.Context{
+		{Short: 'n', Name: "name"},
+		{Short: 'l', Name: "length"},
+		{Short: 'r', Name: "tracks"},
+	}
+	// HelpVerbs are used by key bindings help format.
+	HelpVerbs = format.Context{
+		{Short: 'k', Name: "keys"},
+		{Short: 'c', Name: "command"},
+	}
+)
+
+// defFormats lists format properties with their verbs and default values.
+var defFormats = []struct {
+	Name string
+	Var  *string
+	Ctx  format.Context
+	Def  string
+}{
+	{"browser-dir-format", &FormatBrowserDir, DirVerbs,
+		"{%n}/"},
+	{"browser-track-format", &FormatBrowserTrack, TrackVerbs,
+		"{-*%/e:[%a - ]%t|%f}{20%:%l}"},
+	{"help-format", &FormatHelp, HelpVerbs,
+		"{-30%:%k}{-*%:%c}"},
+	{"playlist-track-format", &FormatPlaylistTrack, TrackVerbs,
+		"{-*%/e:[%a - ]%t|%f}{20%:%l}"},
+	{"playlists-format", &FormatPlaylists, PlaylistsVerbs,
+		"{-*%:%n}{15%:%r}{15%:%l}"},
+	{"status-paused-format", &FormatStatusPaused, PlayerVerbs,
+		"{-*%/e:[%a - ]%t|%f}{*%:%[%o/%l%]}"},
+	{"status-playing-format", &FormatStatusPlaying, PlayerVerbs,
+		"{-*%/e:[%a - ]%t|%f}{*%:%[%o/%l%]}"},
+	{"title-format", &FormatTitle, PlayerVerbs,
+		"{-*%/m:%p}{*%:%[%v%%%]}"},
+}
+
+func initFormats(cfg *config.Config) {
+	for _, f := range defFormats {
+		*f.Var = cfg.StringOr(f.Name, f.Def)
+	}
+}
+
+func parseClock(v any) (any, error) {
+	return v, escapeError(format.ValidateClock(v.(string)))
+}
+
+// formatParser returns property parser which validates format
+// using verbs of the given context.
+func formatParser(ctx format.Context) func(v any) (any, error) {
+	return func(v any) (any, error) {
+		return v, escapeError(format.Validate(v.(string), ctx))
+	}
+}
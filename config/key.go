@@ -0,0 +1,237 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ncurses "github.com/gbin/goncurses"
+)
+
+// keyEsc is an Escape key. Terminals send Meta (Alt) key combinations
+// as Escape followed by the key.
+const keyEsc ncurses.Key = 0x1B
+
+// Named keys. Names are matched case insensitively.
+var keyNames = []struct {
+	Name string
+	Key  ncurses.Key
+}{
+	{"Backspace", ncurses.KEY_BACKSPACE},
+	{"Delete", ncurses.KEY_DC},
+	{"Down", ncurses.KEY_DOWN},
+	{"End", ncurses.KEY_END},
+	{"Enter", ncurses.KEY_RETURN},
+	{"Esc", keyEsc},
+	{"F1", ncurses.KEY_F1},
+	{"F2", ncurses.KEY_F2},
+	{"F3", ncurses.KEY_F3},
+	{"F4", ncurses.KEY_F4},
+	{"F5", ncurses.KEY_F5},
+	{"F6", ncurses.KEY_F6},
+	{"F7", ncurses.KEY_F7},
+	{"F8", ncurses.KEY_F8},
+	{"F9", ncurses.KEY_F9},
+	{"F10", ncurses.KEY_F10},
+	{"F11", ncurses.KEY_F11},
+	{"F12", ncurses.KEY_F12},
+	{"Home", ncurses.KEY_HOME},
+	{"Insert", ncurses.KEY_IC},
+	{"Left", ncurses.KEY_LEFT},
+	{"PageDown", ncurses.KEY_PAGEDOWN},
+	{"PageUp", ncurses.KEY_PAGEUP},
+	{"Right", ncurses.KEY_RIGHT},
+	{"Space", ncurses.Key(' ')},
+	{"Tab", ncurses.KEY_TAB},
+	{"Up", ncurses.KEY_UP},
+}
+
+// KeySeq is a sequence of keys which should be pressed one by one
+// to trigger bound command, e.g. "gg".
+type KeySeq []ncurses.Key
+
+// String returns sequence in the configuration file format. Named keys
+// and Meta combinations are enclosed with angle brackets, e.g.
+// "g<F1><M-x>".
+func (s KeySeq) String() string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == keyEsc && i+1 < len(s) {
+			b.WriteString("<M-" + keyName(s[i+1]) + ">")
+			i++
+		} else if n, ok := namedKey(s[i]); ok {
+			b.WriteString("<" + n + ">")
+		} else {
+			b.WriteString(keyName(s[i]))
+		}
+	}
+
+	return b.String()
+}
+
+// id returns string which uniquely identifies the sequence and
+// can be used as a map key.
+func (s KeySeq) id() string {
+	rs := make([]rune, len(s))
+	for i, k := range s {
+		rs[i] = rune(k)
+	}
+
+	return string(rs)
+}
+
+func keySeqFromID(id string) KeySeq {
+	var s KeySeq
+	for _, r := range id {
+		s = append(s, ncurses.Key(r))
+	}
+
+	return s
+}
+
+// ParseKey parses key sequence definition in the same format keys are
+// defined in the configuration file. Sequence consists of one or more
+// keys. Key is a single char, ^X for control chars, #code for raw
+// ncurses key codes or key name enclosed with angle brackets: <F1>,
+// <PageUp>, <M-x> for Meta (Alt) combinations. Single named key can be
+// written without brackets, e.g. "F1" or "M-x".
+func ParseKey(s string) (KeySeq, error) {
+	if s == "" {
+		return nil, errors.New("empty key")
+	}
+	if seq, ok := parseKeyName(s); ok {
+		return seq, nil
+	}
+
+	var seq KeySeq
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		if rs[i] == '^' && i+1 < len(rs) {
+			seq = append(seq, ctrlKey(rs[i+1]))
+			i += 2
+		} else if rs[i] == '#' && i+1 < len(rs) && isDigit(rs[i+1]) {
+			j := i + 1
+			for j < len(rs) && isDigit(rs[j]) {
+				j++
+			}
+			c, err := strconv.Atoi(string(rs[i+1 : j]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid key: %s", s)
+			}
+			seq = append(seq, ncurses.Key(c))
+			i = j
+		} else if j := closingBracket(rs, i); rs[i] == '<' && j != -1 {
+			name := string(rs[i+1 : j])
+			ks, ok := parseKeyName(name)
+			if !ok {
+				return nil, fmt.Errorf("invalid key name: %s", name)
+			}
+			seq = append(seq, ks...)
+			i = j + 1
+		} else {
+			seq = append(seq, ncurses.Key(rs[i]))
+			i++
+		}
+	}
+
+	return seq, nil
+}
+
+// Bindings returns all key bindings sorted by command.
+func Bindings() []Binding {
+	var bs []Binding
+	for id, a := range keymap {
+		bs = append(bs, Binding{Keys: keySeqFromID(id), Action: a})
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		ai := bs[i].Action.String()
+		aj := bs[j].Action.String()
+		if ai != aj {
+			return ai < aj
+		}
+
+		return bs[i].Keys.String() < bs[j].Keys.String()
+	})
+
+	return bs
+}
+
+// parseKeyName parses single key: char, ^X, #code, key name or Meta
+// combination.
+func parseKeyName(s string) (KeySeq, bool) {
+	rs := []rune(s)
+
+	if len(rs) == 1 {
+		return KeySeq{ncurses.Key(rs[0])}, true
+	}
+	if len(rs) == 2 && rs[0] == '^' {
+		return KeySeq{ctrlKey(rs[1])}, true
+	}
+	if len(rs) > 1 && rs[0] == '#' {
+		c, err := strconv.Atoi(string(rs[1:]))
+		if err == nil {
+			return KeySeq{ncurses.Key(c)}, true
+		}
+	}
+	for _, n := range keyNames {
+		if strings.EqualFold(n.Name, s) {
+			return KeySeq{n.Key}, true
+		}
+	}
+	if len(rs) > 2 && (rs[0] == 'M' || rs[0] == 'A') && rs[1] == '-' {
+		ks, ok := parseKeyName(string(rs[2:]))
+		if ok && len(ks) == 1 {
+			return KeySeq{keyEsc, ks[0]}, true
+		}
+	}
+
+	return nil, false
+}
+
+// closingBracket returns index of the angle bracket which closes
+// the one at the position i or -1.
+func closingBracket(rs []rune, i int) int {
+	if rs[i] != '<' {
+		return -1
+	}
+	for j := i + 1; j < len(rs); j++ {
+		if rs[j] == '>' {
+			if j == i+1 {
+				return -1
+			}
+			return j
+		}
+	}
+
+	return -1
+}
+
+func namedKey(k ncurses.Key) (string, bool) {
+	for _, n := range keyNames {
+		if n.Key == k {
+			return n.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// keyName returns human readable key name in the configuration
+// file format.
+func keyName(k ncurses.Key) string {
+	if n, ok := namedKey(k); ok {
+		return n
+	} else if k < 0x20 {
+		return "^" + string(rune(k+'a'-1))
+	} else if k < 0x7F {
+		return string(rune(k))
+	} else {
+		return "#" + strconv.Itoa(int(k))
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
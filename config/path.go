@@ -7,18 +7,26 @@ import (
 	"strings"
 )
 
-const pathFile = "path"
+// pathFile keeps the last path of the server given with chub-host and
+// chub-port properties. Paths of named servers are kept in pathsDir.
+const (
+	pathFile = "path"
+	pathsDir = "paths"
+)
 
-func LoadPath() (string, error) {
-	cd, err := configDir()
+// LoadPath returns the last browser path saved for the server. Empty
+// server name means the server is not a named profile. Empty path is
+// returned if nothing is saved yet.
+func LoadPath(server string) (string, error) {
+	f, err := pathFileName(server)
 	if err != nil {
 		return "", err
 	}
 
-	d, err := os.ReadFile(filepath.Join(cd, pathFile))
+	d, err := os.ReadFile(f)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return "/", nil
+			return "", nil
 		}
 		return "", err
 	}
@@ -26,11 +34,27 @@ func LoadPath() (string, error) {
 	return strings.TrimSpace(string(d)), err
 }
 
-func SavePath(s string) error {
-	cd, err := configDir()
+// SavePath saves the last browser path of the server.
+func SavePath(server string, s string) error {
+	f, err := pathFileName(server)
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, []byte(s), 0644)
+}
+
+func pathFileName(server string) (string, error) {
+	cd, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if server == "" {
+		return filepath.Join(cd, pathFile), nil
+	}
 
-	return os.WriteFile(filepath.Join(cd, pathFile), []byte(s), 0644)
+	return filepath.Join(cd, pathsDir, server), nil
 }
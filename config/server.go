@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vchimishuk/config"
+)
+
+// Server is a named chub server connection profile defined in
+// the servers block of the configuration file.
+type Server struct {
+	Name string
+	// Host is a host name or an address in any form ParseAddr
+	// accepts. Port is used if the address has no port.
+	Host string
+	Port int
+	// Path is a directory to start browsing from if there is no
+	// last path saved for the server.
+	Path string
+}
+
+var (
+	// Servers lists server profiles in the configuration file order.
+	Servers []*Server
+	// DefaultServer is a name of the server to connect to if no server
+	// is given on the command line. Empty name means chub-host and
+	// chub-port properties are used.
+	DefaultServer string
+)
+
+var serversSpec = &config.BlockSpec{
+	Name: "servers",
+	Blocks: []*config.BlockSpec{
+		&config.BlockSpec{
+			Name:   "*",
+			Repeat: true,
+			Strict: true,
+			Properties: []*config.PropertySpec{
+				&config.PropertySpec{
+					Type:   config.TypeString,
+					Name:   "host",
+					Parser: parseAddr,
+				},
+				&config.PropertySpec{
+					Type: config.TypeInt,
+					Name: "port",
+				},
+				&config.PropertySpec{
+					Type: config.TypeString,
+					Name: "path",
+				},
+			},
+		},
+	},
+	Strict: true,
+}
+
+// FindServer returns server profile by its name or nil if there is
+// no such profile.
+func FindServer(name string) *Server {
+	for _, s := range Servers {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// ServerNames returns names of all server profiles.
+func ServerNames() []string {
+	var names []string
+	for _, s := range Servers {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+// loadServers reads server profiles and validates the default server
+// name. Host and port which are not set in the profile default to
+// chub-host and chub-port properties.
+func loadServers(cfg *config.Config) ([]*Server, string, error) {
+	host := cfg.StringOr("chub-host", defChubHost)
+	port := cfg.IntOr("chub-port", DefaultPort)
+
+	var servers []*Server
+	if b := cfg.Block("servers"); b != nil {
+		for _, sb := range b.Blocks {
+			// Name is used as a file name to save the last path.
+			if strings.ContainsAny(sb.Name, "/.") {
+				return nil, "", fmt.Errorf("invalid server name: %s",
+					sb.Name)
+			}
+			servers = append(servers, &Server{
+				Name: sb.Name,
+				Host: sb.StringOr("host", host),
+				Port: sb.IntOr("port", port),
+				Path: sb.StringOr("path", "/"),
+			})
+		}
+	}
+
+	def := cfg.StringOr("server", "")
+	if def != "" {
+		found := false
+		for _, s := range servers {
+			found = found || s.Name == def
+		}
+		if !found {
+			return nil, "", fmt.Errorf("unknown server: %s", def)
+		}
+	}
+
+	return servers, def, nil
+}
+
+// Addr returns parsed address of the server.
+func (s *Server) Addr() (Addr, error) {
+	return ParseAddr(s.Host, s.Port)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/chubby"
+)
+
+// Delays between reconnection attempts. Delay is doubled after every
+// failed attempt.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+var (
+	// chubMu serializes server commands, so supervisor never replaces
+	// the client in the middle of a command.
+	chubMu sync.Mutex
+	// retryTime is the time of the next connection attempt. It is zero
+	// while connected. Accessed by the UI goroutine only.
+	retryTime time.Time
+	// switches passes the server to switch to from the UI goroutine
+	// to the supervisor.
+	switches = make(chan *config.Server, 1)
+)
+
+// connection is a set up server connection with all the data UI needs
+// to be refreshed after connect.
+type connection struct {
+	client  *chubby.Chubby
+	events  <-chan chubby.Event
+	status  *chubby.Status
+	dir     string
+	entries []chubby.Entry
+}
+
+// connect connects to the server, subscribes to the events and fetches
+// player status and listing of the dir. Root directory is listed if
+// the dir does not exist anymore.
+func connect(srv *config.Server, dir string) (*connection, error) {
+	addr, err := srv.Addr()
+	if err != nil {
+		return nil, err
+	}
+	c := &chubby.Chubby{}
+	err = dial(c, addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &connection{client: c, dir: dir}
+
+	cn.events, err = c.Events(true)
+	if err == nil {
+		cn.status, err = c.Status()
+	}
+	if err == nil {
+		cn.entries, err = c.List(dir)
+		if err != nil && chubby.IsServerError(err) {
+			cn.dir = "/"
+			cn.entries, err = c.List(cn.dir)
+		}
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return cn, nil
+}
+
+// setChub replaces the client used by the server requests. It waits
+// for the request in progress to complete, so it must not be called by
+// the UI goroutine while connected.
+func setChub(c *chubby.Chubby) {
+	chubMu.Lock()
+	chub = c
+	chubMu.Unlock()
+}
+
+// dial connects the client to the server address.
+func dial(c *chubby.Chubby, addr config.Addr) error {
+	network, address := "tcp", addr.String()
+	if addr.Unix() {
+		network, address = "unix", addr.Path
+	}
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return err
+	}
+	err = c.ConnectConn(conn)
+	if err != nil {
+		conn.Close()
+	}
+
+	return err
+}
+
+// use refreshes UI with the connection data. Must be called by the UI
+// goroutine after the connection client is set with setChub.
+func (cn *connection) use() {
+	retryTime = time.Time{}
+	chubStatus = cn.status
+	chubStarted = time.Now().Unix() - int64(cn.status.TrackPos)
+	browserPath = cn.dir
+	browserEntries = cn.entries
+	// Playlists could be changed while we were disconnected.
+	playlistsOutdated = true
+	// Reload the playlist in case it failed to load before.
+	playlistName = ""
+	updateWindows()
+}
+
+// supervise handles events of the connection and reconnects in
+// background when the connection is lost, until quit is closed. It
+// connects to another server if it is requested with reconnect.
+func supervise(cn *connection, srv *config.Server, quit <-chan any,
+	done chan<- any) {
+
+	defer func() {
+		done <- struct{}{}
+	}()
+
+	delay := minRetryDelay
+	for {
+		lost := make(chan any)
+		go func(events <-chan chubby.Event) {
+			handleEvents(events)
+			close(lost)
+		}(cn.events)
+		select {
+		case <-lost:
+		case srv = <-switches:
+			// Only this client is closed, so the new connection
+			// can not be dropped by a late close.
+			chubMu.Lock()
+			cn.client.Close()
+			chubMu.Unlock()
+			<-lost
+		}
+		select {
+		case <-quit:
+			return
+		case srv = <-switches:
+		default:
+		}
+		setRetryTime(time.Now())
+
+		for {
+			dir, ok := currentPath(quit)
+			if !ok {
+				return
+			}
+			var err error
+			cn, err = connect(srv, dir)
+			if err == nil {
+				select {
+				case srv = <-switches:
+					// Server was switched while connecting.
+					cn.client.Close()
+					delay = minRetryDelay
+					continue
+				default:
+				}
+				break
+			}
+
+			setRetryTime(time.Now().Add(delay))
+			t := time.NewTimer(delay)
+			select {
+			case <-quit:
+				t.Stop()
+				return
+			case srv = <-switches:
+				t.Stop()
+				delay = minRetryDelay
+			case <-t.C:
+				delay *= 2
+				if delay > maxRetryDelay {
+					delay = maxRetryDelay
+				}
+			}
+		}
+		delay = minRetryDelay
+
+		select {
+		case <-quit:
+			// Application exited while we were connecting.
+			cn.client.Close()
+			return
+		default:
+		}
+		setChub(cn.client)
+		post(cn.use)
+	}
+}
+
+// reconnect makes supervisor disconnect from the current server and
+// connect to srv in background. Must be called by the UI goroutine.
+func reconnect(srv *config.Server) {
+	// Only the last requested server matters.
+	select {
+	case <-switches:
+	default:
+	}
+	switches <- srv
+}
+
+// setRetryTime updates reconnection countdown in the status bar.
+func setRetryTime(t time.Time) {
+	post(func() {
+		retryTime = t
+		updateStatus()
+	})
+}
+
+// currentPath returns the browser path from the UI goroutine, so
+// the same directory is listed after reconnect. ok is false if quit
+// is closed.
+func currentPath(quit <-chan any) (string, bool) {
+	path := make(chan string, 1)
+	post(func() {
+		path <- browserPath
+	})
+
+	select {
+	case p := <-path:
+		return p, true
+	case <-quit:
+		return "", false
+	}
+}
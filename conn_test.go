@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/chubby"
+)
+
+// serveChub answers the commands connect sends, like chub server does.
+func serveChub(l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewScanner(conn)
+	for r.Scan() {
+		var body string
+		switch strings.Fields(r.Text())[0] {
+		case "status":
+			body = "state: \"stopped\", volume: 50\n"
+		case "list":
+			body = "type: \"dir\", path: \"/a\", name: \"a\"\n" +
+				"path: \"/t.mp3\", artist: \"X\", album: \"Y\", " +
+				"year: 2000, title: \"T\", number: 1, length: 100\n"
+		}
+		conn.Write([]byte("OK\n" + body + "\n"))
+	}
+}
+
+func TestConnectUnix(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "chub.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Error listening %s. %s", sock, err)
+	}
+	defer l.Close()
+	go serveChub(l)
+
+	cn, err := connect(&config.Server{Host: "unix:" + sock}, "/")
+	if err != nil {
+		t.Fatalf("Error connecting to %s. %s", sock, err)
+	}
+	defer cn.client.Close()
+
+	if cn.status.State != chubby.StateStopped || cn.status.Volume != 50 {
+		t.Errorf("Unexpected status: %+v", cn.status)
+	}
+	if len(cn.entries) != 2 || !cn.entries[0].IsDir() ||
+		cn.entries[1].Track().Path != "/t.mp3" {
+
+		t.Errorf("Unexpected entries: %v", cn.entries)
+	}
+}
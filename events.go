@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vchimishuk/chubby"
+)
+
+// pollTimeout is how long the UI goroutine waits for a key press
+// before it processes posted events.
+const pollTimeout = 50 * time.Millisecond
+
+// statusInterval is a period the status bar is updated with, even if
+// nothing is playing to keep the clock running.
+const statusInterval = 900 * time.Millisecond
+
+var (
+	// uiEvents receives events for the UI goroutine. The event is
+	// a function which is executed by the UI goroutine, so it can
+	// change UI state and call ncurses freely. No other goroutine
+	// is allowed to do so.
+	uiEvents = make(chan func(), 64)
+	// uiQuit is closed when the UI goroutine stops processing events.
+	uiQuit = make(chan any)
+)
+
+// post sends the event to the UI goroutine. Event is dropped if
+// the application is exiting.
+func post(e func()) {
+	select {
+	case uiEvents <- e:
+	case <-uiQuit:
+	}
+}
+
+// processEvents executes all posted events. Must be called by the UI
+// goroutine only.
+func processEvents() {
+	for {
+		select {
+		case e := <-uiEvents:
+			e()
+		default:
+			return
+		}
+	}
+}
+
+// tick posts status update periodically.
+func tick() {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			post(updateStatus)
+		case <-uiQuit:
+			return
+		}
+	}
+}
+
+// handleSignals converts terminal resize and configuration reload
+// signals into the UI events.
+func handleSignals(reload func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case sig := <-sigs:
+			switch sig {
+			case syscall.SIGWINCH:
+				post(resetUI)
+			case syscall.SIGHUP:
+				post(reload)
+			}
+		case <-uiQuit:
+			return
+		}
+	}
+}
+
+// handleEvents posts server events to the UI goroutine until
+// the connection is closed.
+func handleEvents(events <-chan chubby.Event) {
+	for e := range events {
+		switch se := e.(type) {
+		case *chubby.CreatePlaylistEvent,
+			*chubby.DeletePlaylistEvent:
+			post(func() {
+				playlistsOutdated = true
+			})
+		case *chubby.StatusEvent:
+			st := &chubby.Status{
+				State:       se.State,
+				Volume:      se.Volume,
+				PlaylistPos: se.PlaylistPos,
+				TrackPos:    se.TrackPos,
+				Playlist:    se.Playlist,
+				Track:       se.Track,
+			}
+			started := time.Now().Unix() - int64(se.TrackPos)
+			post(func() {
+				chubStatus = st
+				chubStarted = started
+				updateStatus()
+			})
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of asp.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// barPart is a bar which fills the space left in the substitution,
+// e.g. "[=====>----]". Data value of the bar verb is a percent of
+// the bar to fill. Bar characters can be changed with options,
+// e.g. %{bar|fill:#|empty:.|head:}.
+type barPart struct {
+	key   string
+	fill  string
+	empty string
+	head  string
+}
+
+func newBarPart(key string) *barPart {
+	return &barPart{key: key, fill: "=", empty: "-", head: ">"}
+}
+
+// setOption sets bar option in name:value form.
+func (p *barPart) setOption(opt string) error {
+	name, val, ok := strings.Cut(opt, ":")
+	if !ok {
+		return fmt.Errorf("Invalid bar option: %s.", opt)
+	}
+	switch name {
+	case "fill":
+		p.fill = val
+	case "empty":
+		p.empty = val
+	case "head":
+		p.head = val
+	default:
+		return fmt.Errorf("Unknown bar option: %s.", name)
+	}
+	// Head can be empty to be omitted, other chars take one cell.
+	if Width(val) != 1 && (name != "head" || val != "") {
+		return fmt.Errorf("Bar %s should be a single char.", name)
+	}
+
+	return nil
+}
+
+func (p *barPart) eval(data map[string]string, barWidth int) (string, bool) {
+	v := data[p.key]
+	if v == "" {
+		return "", false
+	}
+	perc, err := strconv.ParseFloat(v, 64)
+	if err != nil || perc < 0 {
+		perc = 0
+	} else if perc > 100 {
+		perc = 100
+	}
+
+	n := round(float64(barWidth) * perc / 100)
+	if p.head != "" && n > 0 && n < barWidth {
+		return strings.Repeat(p.fill, n-1) + p.head +
+			strings.Repeat(p.empty, barWidth-n), true
+	}
+
+	return strings.Repeat(p.fill, n) + strings.Repeat(p.empty, barWidth-n),
+		true
+}
+
+func (p *barPart) repr() string {
+	return fmt.Sprintf("%%{%s|fill:%s|empty:%s|head:%s}", p.key, p.fill,
+		p.empty, p.head)
+}
+
+// countBars returns number of bars in parts including nested ones.
+func countBars(parts []part) int {
+	n := 0
+	for _, p := range parts {
+		switch p := p.(type) {
+		case *barPart:
+			n++
+		case optPart:
+			n += countBars(p)
+		}
+	}
+
+	return n
+}
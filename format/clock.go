@@ -0,0 +1,85 @@
+// Copyright 2015 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of asp.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockVerbs maps strftime-like clock pattern verbs to the functions
+// formatting corresponding part of the time.
+var clockVerbs = map[byte]func(t time.Time) string{
+	'a': func(t time.Time) string { return t.Format("Mon") },
+	'A': func(t time.Time) string { return t.Format("Monday") },
+	'b': func(t time.Time) string { return t.Format("Jan") },
+	'B': func(t time.Time) string { return t.Format("January") },
+	'd': func(t time.Time) string { return t.Format("02") },
+	'e': func(t time.Time) string { return t.Format("_2") },
+	'H': func(t time.Time) string { return t.Format("15") },
+	'I': func(t time.Time) string { return t.Format("03") },
+	'j': func(t time.Time) string { return t.Format("002") },
+	'm': func(t time.Time) string { return t.Format("01") },
+	'M': func(t time.Time) string { return t.Format("04") },
+	'p': func(t time.Time) string { return t.Format("PM") },
+	'S': func(t time.Time) string { return t.Format("05") },
+	'y': func(t time.Time) string { return t.Format("06") },
+	'Y': func(t time.Time) string { return strconv.Itoa(t.Year()) },
+	'Z': func(t time.Time) string { return t.Format("MST") },
+	'%': func(t time.Time) string { return "%" },
+}
+
+// ValidateClock returns an error if the clock pattern is not valid.
+func ValidateClock(pattern string) error {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(pattern) {
+			return fmt.Errorf("Verb expected after %% at the end.")
+		}
+		if _, ok := clockVerbs[pattern[i]]; !ok {
+			return fmt.Errorf("Unknown clock verb: %%%c.", pattern[i])
+		}
+	}
+
+	return nil
+}
+
+// Clock formats time using strftime-like pattern, e.g. "%H:%M".
+// Supported verbs are %a, %A, %b, %B, %d, %e, %H, %I, %j, %m, %M, %p,
+// %S, %y, %Y, %Z and %%. Unknown verbs are output as is.
+func Clock(pattern string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '%' && i+1 < len(pattern) {
+			if f, ok := clockVerbs[pattern[i+1]]; ok {
+				b.WriteString(f(t))
+				i++
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
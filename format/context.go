@@ -0,0 +1,61 @@
+// Copyright 2015 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of asp.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import "strings"
+
+// Verb describes a format verb. In the format string verb is referenced
+// by its name, e.g. %{artist}, or by its one letter short name if it has
+// one, e.g. %a. Data passed to the Format method is keyed by verb names.
+type Verb struct {
+	Short byte
+	Name  string
+	// Bar verbs are displayed as bars which fill the substitution
+	// width, their values are percents of the bar to fill.
+	Bar bool
+}
+
+// Context is a list of verbs some format can use. Parsing a format which
+// uses a verb the context does not provide fails. nil Context accepts
+// any verb and uses verb as written in the format as the data key.
+type Context []Verb
+
+func (c Context) lookup(key string) (v Verb, ok bool) {
+	for _, v := range c {
+		if v.Name == key || len(key) == 1 && v.Short == key[0] {
+			return v, true
+		}
+	}
+
+	return Verb{}, false
+}
+
+// String returns human readable list of context verbs,
+// e.g. "%a %{artist}, %{remaining}".
+func (c Context) String() string {
+	verbs := make([]string, len(c))
+	for i, v := range c {
+		if v.Short != 0 {
+			verbs[i] = "%" + string(v.Short) + " %{" + v.Name + "}"
+		} else {
+			verbs[i] = "%{" + v.Name + "}"
+		}
+	}
+
+	return strings.Join(verbs, ", ")
+}
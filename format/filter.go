@@ -0,0 +1,138 @@
+// Copyright 2015 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of asp.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// filter is a value transformation applied to the verb value,
+// e.g. %{track|pad:2}. Arguments are separated with colons.
+type filter struct {
+	name string
+	args []string
+}
+
+type filterSpec struct {
+	nargs int
+	// check validates filter arguments, it is optional.
+	check func(args []string) error
+	apply func(s string, args []string) string
+}
+
+var filters = map[string]filterSpec{
+	"base":    {0, nil, filterBase},
+	"dir":     {0, nil, filterDir},
+	"lower":   {0, nil, filterLower},
+	"pad":     {1, checkPad, filterPad},
+	"replace": {2, nil, filterReplace},
+	"title":   {0, nil, filterTitle},
+	"upper":   {0, nil, filterUpper},
+}
+
+// parseFilter parses filter in name[:arg...] form.
+func parseFilter(s string) (filter, error) {
+	pts := strings.Split(s, ":")
+	f := filter{name: pts[0], args: pts[1:]}
+	spec, ok := filters[f.name]
+	if !ok {
+		return filter{}, fmt.Errorf("Unknown filter: %s.", f.name)
+	}
+	if len(f.args) != spec.nargs {
+		return filter{}, fmt.Errorf("Filter %s expects %d arguments.",
+			f.name, spec.nargs)
+	}
+	if spec.check != nil {
+		if err := spec.check(f.args); err != nil {
+			return filter{}, err
+		}
+	}
+
+	return f, nil
+}
+
+func (f filter) apply(s string) string {
+	return filters[f.name].apply(s, f.args)
+}
+
+func (f filter) repr() string {
+	return strings.Join(append([]string{f.name}, f.args...), ":")
+}
+
+func filterBase(s string, args []string) string {
+	return path.Base(s)
+}
+
+func filterDir(s string, args []string) string {
+	return path.Dir(s)
+}
+
+func filterLower(s string, args []string) string {
+	return strings.ToLower(s)
+}
+
+func checkPad(args []string) error {
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return fmt.Errorf("Invalid pad width: %s.", args[0])
+	}
+
+	return nil
+}
+
+// filterPad pads the value with leading zeros.
+func filterPad(s string, args []string) string {
+	n, _ := strconv.Atoi(args[0])
+	if l := utf8.RuneCountInString(s); l < n {
+		return strings.Repeat("0", n-l) + s
+	}
+
+	return s
+}
+
+func filterReplace(s string, args []string) string {
+	if args[0] == "" {
+		return s
+	}
+
+	return strings.ReplaceAll(s, args[0], args[1])
+}
+
+// filterTitle upper cases the first letter of every word.
+// Other letters are left untouched.
+func filterTitle(s string, args []string) string {
+	prev := ' '
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(prev) {
+			prev = r
+			return unicode.ToUpper(r)
+		}
+		prev = r
+		return r
+	}, s)
+}
+
+func filterUpper(s string, args []string) string {
+	return strings.ToUpper(s)
+}
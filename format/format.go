@@ -17,12 +17,7 @@
 
 package format
 
-// TODO: Alternative if missed or empty.
-
-import (
-	"strings"
-	"unicode/utf8"
-)
+import "sort"
 
 // Formatter allows format structs into string.
 // Formatting rules are described with special mini language which allows
@@ -38,8 +33,39 @@ import (
 // width can be set in percents instead of absolute value. Percent width values
 // are calculated during formatter creation depends on the given parameters.
 // "format" is a format string like in Printf is used. Format verbs should be
-// prefixed with percent sign. Available format verb values depends on data
-// map parameter passed to the Format method.
+// prefixed with percent sign. Verb is either a single letter, e.g. %a, or
+// a name in curly braces, e.g. %{artist}. Available format verb values
+// depends on data map parameter passed to the Format method and the
+// format Context.
+// Width can be followed by truncation mode which is used if the text does
+// not fit: /e replaces the end of the text with ellipsis, /s the start
+// and /m the middle of the text. Default is to cut the end of the text.
+// Priority can be set with !N suffix. If the line is too narrow
+// substitutions with lower priority are shrunk first, e.g. "{-*%/m!1:%p}".
+// Substitutions without a priority have the lowest priority of zero.
+// Verb can be followed by fallback verbs separated with | sign which are
+// used if the value is missed or empty, e.g. "%t|%f". Part of the format
+// surrounded with square brackets is an optional section which is omitted
+// entirely if any verb inside it is missed or empty, e.g. "[%a - ]%t".
+// %%, %[, %] and %| are used to output literal %, [, ] and | chars.
+// Verb name in curly braces can be followed by filters which transform
+// the value, e.g. %{track|pad:2} or %{path|dir|base}. Filter arguments
+// are separated with colons. Filters are: pad:N pads the value with
+// leading zeros to N chars, upper, lower, title change the case, base
+// and dir return the last element and the directory of the path,
+// replace:OLD:NEW replaces all OLD substrings with NEW. Arguments can
+// not contain :, | and } chars.
+// Verbs marked as bars in the Context are displayed as bars which fill
+// the space left in the substitution, e.g. "{*%:%[%{bar}%]}" is displayed
+// as "[=====>----]". Bar value is a percent of the bar to fill. Bar chars
+// can be changed with fill, empty and head options, e.g.
+// %{bar|fill:#|empty:.|head:}, empty head is omitted. Substitution
+// without width leaves no space for bars.
+// Style markup in curly braces prefixed with $ sign sets style of the
+// text which follows it. It is a comma separated list of attributes (bold,
+// dim, underline, reverse, blink, standout) and a foreground color: basic
+// color name, default, 256-color palette index or #rrggbb value.
+// {$reset} returns to the default style, e.g. "{$bold}{%a}{$reset} {%t}".
 // Examples.
 // If data is map[string]string{
 //         "a": "A",
@@ -49,15 +75,43 @@ import (
 // "foo {%a} bar {%b}" formatted to "foo A bar B"
 // "{{{10:%a-%b}}}" formatted to "{       A-B}"
 // "{{{-10:%a-%b}}}" formatted to "{A-B       }"
+// "{[%c - ]%a}" formatted to "A"
+// "{%c|%b}" formatted to "B"
+// "{5/e:%a%b%a%b}" formatted to "ABAB" and to "AB…" with width 3
+//
+// Formatter is not safe for concurrent use.
 type Formatter interface {
+	// Format returns formatted text which takes exactly width
+	// terminal cells. Style markup is ignored.
 	Format(data map[string]string, width int) string
+	// FormatSpans is like Format but splits the text into spans
+	// according to the style markup.
+	FormatSpans(data map[string]string, width int) []Span
 }
 
+// cacheSize is a number of widths formatter keeps compiled nodes for.
+// Usually the same format is used by a window or two of the same width,
+// a few more entries cover the resize.
+const cacheSize = 4
+
 type formatter struct {
 	// format is the formatting pattern.
 	format string
+	ctx    Context
 	// To prevent format compilation every time we compile
 	// it once per width and cache it.
+	cache [cacheSize]compiled
+	// next is the cache entry to be replaced next.
+	next int
+	// strs, ends and buf are reused between calls not to allocate
+	// them for every formatted line.
+	strs []string
+	ends []int
+	buf  []byte
+}
+
+// compiled is a format compiled for the given width.
+type compiled struct {
 	nodes []node
 	width int
 }
@@ -65,60 +119,175 @@ type formatter struct {
 // Validate returns an error if given not valid formatter string.
 // Validate usually should be called before NewFormatter to be sure that
 // runtime error will not happend during formatting later.
-func Validate(format string) error {
-	_, err := parse(format, 100)
+func Validate(format string, ctx Context) error {
+	_, err := parse(format, 100, ctx)
 
 	return err
 }
 
 // NewFormatter returns new Formatter object for the given format pattern.
-func NewFormatter(format string) Formatter {
-	return &formatter{format: format}
+// Verbs are resolved to the data keys using the given context.
+func NewFormatter(format string, ctx Context) Formatter {
+	return &formatter{format: format, ctx: ctx}
 }
 
 func (f *formatter) Format(data map[string]string, width int) string {
-	if f.width != width {
-		f.nodes = nil
+	nodes := f.nodes(width)
+	strs := f.eval(nodes, data, width)
+
+	return string(f.fit(strs, width))
+}
+
+func (f *formatter) FormatSpans(data map[string]string, width int) []Span {
+	nodes := f.nodes(width)
+	strs := f.eval(nodes, data, width)
+	// All spans share the single line string.
+	line := string(f.fit(strs, width))
+
+	return spans(nodes, line, f.ends)
+}
+
+// fit joins formatted nodes into the reused buffer. Resulting text is
+// cut to fit to the required width and padded with spaces if it is
+// shorter. ends receives the end offset of every node text in the line.
+func (f *formatter) fit(strs []string, width int) []byte {
+	if cap(f.ends) < len(strs) {
+		f.ends = make([]int, len(strs))
+	}
+	f.ends = f.ends[:len(strs)]
+	b := f.buf[:0]
+	left := width
+	cut := false
+
+	for i, s := range strs {
+		if !cut {
+			t := head(s, left)
+			b = append(b, t...)
+			left -= Width(t)
+			// Double-width char may not fit leaving one cell
+			// free, following nodes must not be shown after
+			// it anyway.
+			cut = t != s
+		}
+		f.ends[i] = len(b)
 	}
-	if f.nodes == nil {
-		nodes, err := parse(f.format, width)
-		if err != nil {
-			panic(err)
+	for ; left > 0; left-- {
+		b = append(b, ' ')
+	}
+	f.buf = b
+
+	return b
+}
+
+// nodes returns format nodes compiled for the given width.
+func (f *formatter) nodes(width int) []node {
+	for _, c := range f.cache {
+		if c.nodes != nil && c.width == width {
+			return c.nodes
 		}
-		f.nodes = nodes
-		f.width = width
 	}
 
-	res := ""
-	for _, n := range f.nodes {
-		res += n.format(data)
+	nodes, err := parse(f.format, width, f.ctx)
+	if err != nil {
+		panic(err)
 	}
+	f.cache[f.next] = compiled{nodes, width}
+	f.next = (f.next + 1) % cacheSize
 
-	// Resulting text should fit to the required width.
-	l := utf8.RuneCountInString(res)
-	if l < f.width {
-		res += strings.Repeat(" ", f.width-l)
-	} else {
-		res = truncate(res, f.width)
+	return nodes
+}
+
+// eval formats every node and shrinks the result if it does not fit
+// to the width. Returned slice is reused by the next call.
+func (f *formatter) eval(nodes []node, data map[string]string,
+	width int) []string {
+
+	if cap(f.strs) < len(nodes) {
+		f.strs = make([]string, len(nodes))
+	}
+	strs := f.strs[:len(nodes)]
+	total := 0
+	for i, n := range nodes {
+		strs[i] = n.format(data)
+		total += Width(strs[i])
+	}
+	if total > width {
+		shrink(nodes, strs, data, total-width)
 	}
 
-	return res
+	return strs
 }
 
-func truncate(s string, size int) string {
-	bytes := 0
-	runes := 0
-	p := s
-	for len(p) > 0 && runes < size {
-		_, size := utf8.DecodeRuneInString(p)
-		bytes += size
-		runes += 1
-		p = p[size:]
+// shrink shrinks formatted substitutions by overflow chars in total.
+// Substitutions with lower priority are shrunk first, the last one
+// first among substitutions with the same priority.
+func shrink(nodes []node, strs []string, data map[string]string,
+	overflow int) {
+
+	var subst []int
+	for i, n := range nodes {
+		if _, ok := n.(*substNode); ok {
+			subst = append(subst, i)
+		}
 	}
+	sort.SliceStable(subst, func(i, j int) bool {
+		pi := nodes[subst[i]].(*substNode).prio
+		pj := nodes[subst[j]].(*substNode).prio
+		if pi != pj {
+			return pi < pj
+		}
+		return subst[i] > subst[j]
+	})
 
-	if bytes < len(s) {
-		return s[:bytes]
-	} else {
+	for _, i := range subst {
+		if overflow <= 0 {
+			break
+		}
+		l := Width(strs[i])
+		cut := min(l, overflow)
+		strs[i] = nodes[i].(*substNode).formatWidth(data, l-cut)
+		overflow -= cut
+	}
+}
+
+// Truncation modes.
+const (
+	// truncCut cuts the end of the text.
+	truncCut = 0
+	// truncEnd replaces the end of the text with ellipsis.
+	truncEnd = 'e'
+	// truncStart replaces the start of the text with ellipsis.
+	truncStart = 's'
+	// truncMiddle replaces the middle of the text with ellipsis,
+	// which suits paths well.
+	truncMiddle = 'm'
+)
+
+// truncModes lists truncation modes available in width specification.
+const truncModes = "esm"
+
+const ellipsis = "…"
+
+// truncate truncates s to fit to the given number of terminal cells
+// using the truncation mode. Double-width characters are never split,
+// so the result can be one cell shorter than requested.
+func truncate(s string, size int, mode byte) string {
+	if Width(s) <= size {
 		return s
 	}
+	if size <= 0 {
+		return ""
+	}
+
+	switch mode {
+	case truncEnd:
+		return head(s, size-1) + ellipsis
+	case truncStart:
+		return ellipsis + tail(s, size-1)
+	case truncMiddle:
+		h := size / 2
+		return head(s, h) + ellipsis + tail(s, size-1-h)
+	default:
+		return head(s, size)
+	}
 }
@@ -17,17 +17,25 @@
 
 package format
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+)
 
 var data = map[string]string{
 	"a": "A",
 	"t": "T",
 	"k": "кириллица",
 	"n": "latinicca",
+	"j": "日本語",
+	"e": "été",
+	"d": "3",
+	"p": "/music/some artist/01 song.flac",
 }
 
 func testFormat(t *testing.T, format string, width int, expected string) {
-	f := NewFormatter(format)
+	f := NewFormatter(format, nil)
 	actual := f.Format(data, width)
 
 	if expected != actual {
@@ -71,4 +79,248 @@ func TestFormat(t *testing.T) {
 		"кириллица                      кириллица")
 	testFormat(t, "{-50%:%k}{50%:test}", 15,
 		"кирилли    test")
+	testFormat(t, "{[%a - ]%t}", 7,
+		"A - T  ")
+	testFormat(t, "{[%x - ]%t}", 7,
+		"T      ")
+	testFormat(t, "{%x|%t}", 3,
+		"T  ")
+	testFormat(t, "{%x|%y}", 3,
+		"   ")
+	testFormat(t, "{5:[%a[%x]!]}", 5,
+		"   A!")
+	testFormat(t, "{%[%a%]}", 3,
+		"[A]")
+	testFormat(t, "{%{a} - %{t}}", 7,
+		"A - T  ")
+	testFormat(t, "{%k}", 5,
+		"кирил")
+	testFormat(t, "{/e:%k}", 5,
+		"кири…")
+	testFormat(t, "{/s:%k}", 5,
+		"…лица")
+	testFormat(t, "{/m:%k}", 5,
+		"ки…ца")
+	testFormat(t, "{-12/m:%n}", 8,
+		"lati…cca")
+	testFormat(t, "{%n} {%k}", 12,
+		"latinicca ки")
+	testFormat(t, "{!1:%n} {%k}", 12,
+		"latinicca ки")
+	testFormat(t, "{%n} {!1:%k}", 12,
+		"la кириллица")
+	testFormat(t, "{/e:%n} {!1:%k}", 12,
+		"l… кириллица")
+	testFormat(t, "{/e!1:%n} {!2:%k} {%a}", 12,
+		"… кириллица ")
+	testFormat(t, "{%j}", 8,
+		"日本語  ")
+	testFormat(t, "{%j}", 5,
+		"日本 ")
+	testFormat(t, "{-4:%j}|", 5,
+		"日本|")
+	testFormat(t, "{-5:%j}|", 6,
+		"日本 |")
+	testFormat(t, "{/e:%j}", 4,
+		"日… ")
+	testFormat(t, "{/s:%j}", 4,
+		"…語 ")
+	testFormat(t, "{-*%:%j}{*%:%a}", 8,
+		"日本   A")
+	testFormat(t, "{%e}", 4,
+		"e\u0301te\u0301 ")
+	testFormat(t, "{%e}", 2,
+		"e\u0301t")
+	testFormat(t, "{/s:%e}", 2,
+		"…e\u0301")
+}
+
+func TestFormatFilters(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"{%{d|pad:2}}", "03"},
+		{"{%{n|pad:2}}", "latinicca"},
+		{"{%{d|pad:0}}", "3"},
+		{"{%{n|upper}}", "LATINICCA"},
+		{"{%{k|upper}}", "КИРИЛЛИЦА"},
+		{"{%{a|lower}}", "a"},
+		{"{%{p|base}}", "01 song.flac"},
+		{"{%{p|dir}}", "/music/some artist"},
+		{"{%{p|dir|base|title}}", "Some Artist"},
+		{"{%{p|replace:/:-}}", "-music-some artist-01 song.flac"},
+		{"{%{n|replace:ic:}}", "latinca"},
+		{"{%{x|upper}|%{a|lower}}", "a"},
+		{"{[%{x|pad:2}. ]%t}", "T"},
+	}
+	for _, test := range tests {
+		actual := NewFormatter(test.format, nil).Format(data,
+			Width(test.expected))
+		if test.expected != actual {
+			t.Errorf("Format error: %s.\nExpected: '%s'\n"+
+				"Actual: '%s'", test.format, test.expected, actual)
+		}
+	}
+}
+
+func TestFormatBar(t *testing.T) {
+	ctx := Context{{Short: 'a', Name: "a"}, {Name: "bar", Bar: true},
+		{Name: "vbar", Bar: true}, {Name: "x"}}
+	data := map[string]string{"a": "A", "bar": "50", "vbar": "100"}
+	tests := []struct {
+		format   string
+		width    int
+		expected string
+	}{
+		{"{*%:%{bar}}", 10, "====>-----"},
+		{"{*%:%[%{bar}%]}", 6, "[=>--]"},
+		{"{*%:%{bar|fill:#|empty:.|head:}}", 4, "##.."},
+		{"{*%:%{vbar}}", 4, "===="},
+		{"{*%:%{bar}%{vbar}}", 9, " =>--===="},
+		{"{*%:%a %{bar}}", 6, "A =>--"},
+		{"{*%:[%{x}]%{bar}}", 4, "=>--"},
+		{"{%a%{bar}}", 4, "A   "},
+	}
+	for _, test := range tests {
+		actual := NewFormatter(test.format, ctx).Format(data,
+			test.width)
+		if test.expected != actual {
+			t.Errorf("Format error: %s.\nExpected: '%s'\n"+
+				"Actual: '%s'", test.format, test.expected, actual)
+		}
+	}
+
+	for _, s := range []string{"{%{bar}|%a}", "{%a|%{bar}}",
+		"{%{bar|upper}}", "{%{bar|fill:}}", "{%{bar|fill:==}}",
+		"{%{a|fill:#}}"} {
+		if err := Validate(s, ctx); err == nil {
+			t.Errorf("Error expected parsing string \"%s\".", s)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	tm := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.UTC)
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"%H:%M", "14:07"},
+		{"%I:%M:%S %p", "02:07:09 PM"},
+		{"%a %e %b %Y", "Tue  5 Mar 2024"},
+		{"%A, %d.%m.%y", "Tuesday, 05.03.24"},
+		{"%j %Z 100%%", "065 UTC 100%"},
+	}
+	for _, test := range tests {
+		if err := ValidateClock(test.pattern); err != nil {
+			t.Errorf("Error parsing clock \"%s\". %s",
+				test.pattern, err)
+		}
+		actual := Clock(test.pattern, tm)
+		if test.expected != actual {
+			t.Errorf("Clock error: %s.\nExpected: '%s'\n"+
+				"Actual: '%s'", test.pattern, test.expected, actual)
+		}
+	}
+
+	for _, s := range []string{"%", "%H:%", "%Q"} {
+		if err := ValidateClock(s); err == nil {
+			t.Errorf("Error expected parsing clock \"%s\".", s)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"кириллица", 9},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"e\u0301", 1},
+		{"a\u200db", 2},
+		{"🎵 music", 8},
+		{"\t", 0},
+	}
+	for _, test := range tests {
+		if w := Width(test.s); w != test.width {
+			t.Errorf("Width of %q expected %d, got %d",
+				test.s, test.width, w)
+		}
+	}
+}
+
+func TestFormatSpans(t *testing.T) {
+	tests := []struct {
+		format   string
+		width    int
+		expected []Span
+	}{
+		{"{%a}", 3, []Span{{"A  ", Style{}}}},
+		{"{$bold}{%a}{$reset} - {$cyan,underline}{%t}", 7,
+			[]Span{{"A", Style{Attrs: AttrBold}},
+				{" - ", Style{}},
+				{"T", Style{Fg: "cyan", Attrs: AttrUnderline}},
+				{"  ", Style{}}}},
+		{"{$196}{-*%:%n}{$#00ff00}{*%:%a}", 6,
+			[]Span{{"lat", Style{Fg: "196"}},
+				{"  A", Style{Fg: "#00ff00"}}}},
+		{"{$dim}{%n}{$reset}{%k}", 5,
+			[]Span{{"latin", Style{Attrs: AttrDim}}}},
+	}
+	for _, test := range tests {
+		actual := NewFormatter(test.format, nil).FormatSpans(data,
+			test.width)
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("Format spans error: %s.\nExpected: %v\n"+
+				"Actual: %v", test.format, test.expected, actual)
+		}
+	}
+
+	for _, s := range []string{"{$}", "{$foo}", "{$bold,#fff}",
+		"{$256}"} {
+		if err := Validate(s, nil); err == nil {
+			t.Errorf("Error expected parsing string \"%s\".", s)
+		}
+	}
+}
+
+var benchData = map[string]string{
+	"artist": "Some Artist",
+	"title":  "Some quite long track title",
+	"file":   "01 - some quite long track title.flac",
+	"length": "4:33",
+}
+
+const benchFormat = "{-*%/e:[%{artist} - ]%{title}|%{file}}{20%:%{length}}"
+
+func BenchmarkFormat(b *testing.B) {
+	f := NewFormatter(benchFormat, nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f.Format(benchData, 80)
+	}
+}
+
+func BenchmarkFormatSpans(b *testing.B) {
+	f := NewFormatter("{$bold}"+benchFormat, nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f.FormatSpans(benchData, 80)
+	}
+}
+
+// BenchmarkFormatWidths formats with different widths in turn, like
+// windows of different sizes sharing the same format do.
+func BenchmarkFormatWidths(b *testing.B) {
+	f := NewFormatter(benchFormat, nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f.Format(benchData, 60+i%2*20)
+	}
 }
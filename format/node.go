@@ -18,9 +18,8 @@
 package format
 
 import (
-	"fmt"
 	"strconv"
-	"unicode/utf8"
+	"strings"
 )
 
 // node is a AST like node in which format expression is parsed.
@@ -54,47 +53,66 @@ type substNode struct {
 	width     int
 	percent   bool
 	alignLeft bool
-	fmt       string
-	keys      []string
+	// trunc is a truncation mode, one of truncModes or 0 to just cut
+	// the text at the width.
+	trunc byte
+	// prio is a priority of the node. Nodes with lower priority are
+	// shrunk first if the line is too narrow to fit all nodes.
+	prio int
+	body []part
 }
 
 func newSubstNode(width int, percent bool, alignLeft bool,
-	fmt string, keys []string) node {
+	body ...part) node {
 
 	return &substNode{width: width,
 		percent:   percent,
 		alignLeft: alignLeft,
-		fmt:       fmt,
-		keys:      keys}
+		body:      body}
 }
 
 func (n *substNode) format(data map[string]string) string {
-	var f string
-
-	if n.width != -1 {
-		var sign string
+	return n.formatWidth(data, n.width)
+}
 
-		if n.alignLeft {
-			sign = "-"
-		} else {
-			sign = ""
-		}
-		f = fmt.Sprintf("%%%s%ds", sign, n.width)
-	} else {
-		f = "%s"
+// formatWidth formats node to fit the given width instead of
+// the node's one. -1 width means no width limit.
+func (n *substNode) formatWidth(data map[string]string, width int) string {
+	if width == -1 {
+		s, _ := evalParts(n.body, data, 0)
+		return s
 	}
-
-	params := make([]interface{}, 0, len(n.keys))
-	for _, k := range n.keys {
-		params = append(params, data[k])
+	// Bars share the space left by the rest of the body.
+	bw := 0
+	if nb := countBars(n.body); nb > 0 {
+		rest, _ := evalParts(n.body, data, 0)
+		bw = max(width-Width(rest), 0) / nb
 	}
+	s, _ := evalParts(n.body, data, bw)
 
-	s := fmt.Sprintf(f, fmt.Sprintf(n.fmt, params...))
-	if n.width != -1 && utf8.RuneCountInString(s) > n.width {
-		return string([]rune(s)[:n.width])
-	} else {
+	s = truncate(s, width, n.trunc)
+	// Substitution without width is not aligned, it is only
+	// truncated if the line is too narrow.
+	if n.width == -1 {
+		return s
+	}
+	pad := width - Width(s)
+	if pad <= 0 {
 		return s
 	}
+	var b strings.Builder
+	b.Grow(len(s) + pad)
+	if n.alignLeft {
+		b.WriteString(s)
+	}
+	for i := 0; i < pad; i++ {
+		b.WriteByte(' ')
+	}
+	if !n.alignLeft {
+		b.WriteString(s)
+	}
+
+	return b.String()
 }
 
 func (n *substNode) repr() string {
@@ -114,8 +132,139 @@ func (n *substNode) repr() string {
 		}
 		repr += "*%"
 	}
+	if n.trunc != 0 {
+		repr += "/" + string(n.trunc)
+	}
+	if n.prio != 0 {
+		repr += "!" + strconv.Itoa(n.prio)
+	}
 
-	repr += ":" + n.fmt + "}"
+	repr += ":" + reprParts(n.body) + "}"
 
 	return repr
 }
+
+// part is an element of the substitution body.
+type part interface {
+	// eval evaluates the part. ok is false if some verb used by the
+	// part is missed or empty. barWidth is a width of every bar
+	// inside the part.
+	eval(data map[string]string, barWidth int) (s string, ok bool)
+	repr() string
+}
+
+func evalParts(parts []part, data map[string]string,
+	barWidth int) (string, bool) {
+
+	// Most substitutions consist of a single verb.
+	if len(parts) == 1 {
+		return parts[0].eval(data, barWidth)
+	}
+	var b strings.Builder
+	ok := true
+
+	for _, p := range parts {
+		s, pok := p.eval(data, barWidth)
+		b.WriteString(s)
+		ok = ok && pok
+	}
+
+	return b.String(), ok
+}
+
+func reprParts(parts []part) string {
+	repr := ""
+	for _, p := range parts {
+		repr += p.repr()
+	}
+
+	return repr
+}
+
+// textPart is a plain text inside the substitution.
+type textPart string
+
+func (p textPart) eval(data map[string]string, barWidth int) (string, bool) {
+	return string(p), true
+}
+
+func (p textPart) repr() string {
+	r := strings.NewReplacer("%", "%%", "[", "%[", "]", "%]", "|", "%|")
+
+	return r.Replace(string(p))
+}
+
+// verb is a reference to the data value with optional filters
+// applied to it.
+type verb struct {
+	key     string
+	filters []filter
+}
+
+func (v verb) repr() string {
+	if len(v.key) == 1 && len(v.filters) == 0 {
+		return "%" + v.key
+	}
+	repr := "%{" + v.key
+	for _, f := range v.filters {
+		repr += "|" + f.repr()
+	}
+
+	return repr + "}"
+}
+
+// verbPart is a verb substituted with the data value. If the value is
+// missed or empty the next alternative verb is used, e.g. %t|%f.
+type verbPart []verb
+
+func newVerbPart(keys ...string) part {
+	p := make(verbPart, len(keys))
+	for i, k := range keys {
+		p[i] = verb{key: k}
+	}
+
+	return p
+}
+
+func (p verbPart) eval(data map[string]string, barWidth int) (string, bool) {
+	for _, vb := range p {
+		if v := data[vb.key]; v != "" {
+			for _, f := range vb.filters {
+				v = f.apply(v)
+			}
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+func (p verbPart) repr() string {
+	alts := make([]string, len(p))
+	for i, vb := range p {
+		alts[i] = vb.repr()
+	}
+
+	return strings.Join(alts, "|")
+}
+
+// optPart is an optional section which is omitted entirely if any
+// verb inside it is missed or empty, e.g. [%a - ]%t.
+type optPart []part
+
+func newOptPart(parts ...part) part {
+	return optPart(parts)
+}
+
+func (p optPart) eval(data map[string]string, barWidth int) (string, bool) {
+	s, ok := evalParts(p, data, barWidth)
+	if !ok {
+		return "", true
+	}
+
+	return s, true
+}
+
+func (p optPart) repr() string {
+	return "[" + reprParts(p) + "]"
+}
@@ -20,7 +20,6 @@ package format
 import (
 	"fmt"
 	"math"
-	"regexp"
 	"strconv"
 	"strings"
 )
@@ -31,7 +30,7 @@ const (
 	stateSubst
 )
 
-func parse(s string, width int) (nodes []node, err error) {
+func parse(s string, width int, ctx Context) (nodes []node, err error) {
 	i := 0
 	l := len(s)
 	state := stateText
@@ -43,13 +42,13 @@ func parse(s string, width int) (nodes []node, err error) {
 
 		switch state {
 		case stateSubst:
-			n, read, err = parseSubst(s, i)
+			n, read, err = parseSubst(s, i, ctx)
 			state = stateText
 		case stateText:
 			n, read, err = parseText(s, i)
 			state = stateSubst
 			// If text was started with empty text node.
-			if read == 0 {
+			if read == 0 && err == nil {
 				continue
 			}
 		default:
@@ -89,7 +88,9 @@ func parse(s string, width int) (nodes []node, err error) {
 				staticPercWidth += sn.width
 			}
 		} else if tn, ok := n.(textNode); ok {
-			staticTextWidth += len(tn)
+			staticTextWidth += Width(string(tn))
+		} else if _, ok := n.(*styleNode); ok {
+			// Style markup takes no space.
 		} else {
 			panic(nil)
 		}
@@ -142,6 +143,13 @@ func parse(s string, width int) (nodes []node, err error) {
 	if last != nil {
 		last.width += percWidth - actPercWidthSum
 	}
+	// Static fields may not leave any space for percent ones on
+	// narrow lines. They are shrunk during formatting then.
+	for _, n := range nodes {
+		if sn, ok := n.(*substNode); ok && sn.percent && sn.width < 0 {
+			sn.width = 0
+		}
+	}
 
 	return nodes, nil
 }
@@ -177,66 +185,294 @@ loop:
 	return newTextNode(tok), i, nil
 }
 
-func parseSubst(s string, pos int) (n node, read int, err error) {
+func parseSubst(s string, pos int, ctx Context) (n node, read int,
+	err error) {
+
 	s = s[pos:]
 	if !strings.HasPrefix(s, "{") {
 		panic(nil)
 	}
 
-	i := strings.IndexRune(s, '}')
+	i := substEnd(s)
 	if i == -1 {
 		return nil, 0, fmt.Errorf("} expected but end of text reached.")
 	}
 
 	read = i + 1
 	tok := s[1:i]
-	parts := strings.Split(tok, ":")
+	if strings.HasPrefix(tok, "$") {
+		n, err := newStyleNode(tok[1:])
+		return n, read, err
+	}
+	// Width is separated from the body with the first colon. Body
+	// may contain colons too, so the prefix is treated as a width
+	// only if it looks like one.
+	parts := strings.SplitN(tok, ":", 2)
+	if len(parts) == 2 && (parts[0] == "" ||
+		strings.IndexByte("-*/!0123456789", parts[0][0]) == -1) {
+
+		parts = []string{tok}
+	}
 
 	switch len(parts) {
 	case 1:
-		format, keys, err := parseSubstFormat(parts[0])
+		body, err := parseSubstFormat(parts[0], ctx)
 		if err != nil {
 			return nil, read, err
 		}
-		n = newSubstNode(-1, false, false, format, keys)
+		n = newSubstNode(-1, false, false, body...)
 	case 2:
-		width, percent, alignLeft, err := parseSubstStarWidth(parts[0])
+		spec, mode, prio, err := parseSubstModifiers(parts[0])
 		if err != nil {
-			width, percent, alignLeft, err = parseSubstNumWidth(parts[0])
+			return nil, read, err
+		}
+		width, percent, alignLeft := -1, false, false
+		if spec != "" {
+			width, percent, alignLeft, err = parseSubstStarWidth(spec)
 			if err != nil {
-				return nil, read, err
+				width, percent, alignLeft, err =
+					parseSubstNumWidth(spec)
+				if err != nil {
+					return nil, read, err
+				}
 			}
 		}
-		format, keys, err := parseSubstFormat(parts[1])
+		body, err := parseSubstFormat(parts[1], ctx)
 		if err != nil {
 			return nil, read, err
 		}
-		n = newSubstNode(width, percent, alignLeft, format, keys)
-	default:
-		return nil, read, fmt.Errorf(
-			"Illegal formatter substitution format. Column: %d.", pos)
+		sn := newSubstNode(width, percent, alignLeft, body...).(*substNode)
+		sn.trunc = mode
+		sn.prio = prio
+		n = sn
 	}
 
 	return n, read, nil
 }
 
-func parseSubstFormat(s string) (format string, keys []string, err error) {
-	format = s
-	keys = make([]string, 0, 2)
-	re := regexp.MustCompile("%[\\w%]")
-	indexes := re.FindAllStringIndex(s, -1)
+// substEnd returns index of the closing brace of the substitution
+// which starts at the beginning of s, or -1 if it is not closed.
+// Braces of %{name} verbs are skipped.
+func substEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '%':
+			if i+1 < len(s) && s[i+1] == '{' {
+				j := strings.IndexByte(s[i:], '}')
+				if j == -1 {
+					return -1
+				}
+				i += j
+			} else {
+				i++
+			}
+		case '}':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseSubstFormat parses substitution body which consists of text,
+// %x and %{name} verbs with optional |%y fallbacks and [...] optional
+// sections. %%, %[, %] and %| are used for literal %, [, ] and | chars.
+func parseSubstFormat(s string, ctx Context) (parts []part, err error) {
+	parts, read, err := parseParts(s, 0, false, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if read < len(s) {
+		return nil, fmt.Errorf("Unexpected ] at position %d.", read)
+	}
+
+	return parts, nil
+}
+
+// parseParts parses body parts starting from pos till the end of the
+// string or the closing bracket of the optional section if nested.
+func parseParts(s string, pos int, nested bool, ctx Context) (parts []part,
+	read int, err error) {
 
-	for _, index := range indexes {
-		beg := index[0]
-		v := rune(s[beg+1])
+	text := ""
+	flush := func() {
+		if text != "" {
+			parts = append(parts, textPart(text))
+			text = ""
+		}
+	}
+	i := pos
 
-		if v != '%' {
-			keys = append(keys, string(v))
-			format = format[:beg+1] + "s" + format[beg+2:]
+	for i < len(s) {
+		c := s[i]
+		var next byte
+		if i+1 < len(s) {
+			next = s[i+1]
+		}
+
+		switch {
+		case c == '%' && next != 0 && strings.IndexByte("%[]|", next) != -1:
+			text += string(next)
+			i += 2
+		case c == '%' && (isVerbChar(next) || next == '{'):
+			flush()
+			var vp verbPart
+			for {
+				vb, bar, n, err := parseVerb(s, i, ctx)
+				if err != nil {
+					return nil, i, err
+				}
+				if bar != nil {
+					i += n
+					if len(vp) > 0 || i < len(s) && s[i] == '|' {
+						return nil, i, fmt.Errorf("Bar can not "+
+							"be used with fallbacks at position %d.",
+							i)
+					}
+					parts = append(parts, bar)
+					break
+				}
+				vp = append(vp, vb)
+				i += n
+				if i >= len(s) || s[i] != '|' {
+					break
+				}
+				i++
+				if i >= len(s) || s[i] != '%' {
+					return nil, i, fmt.Errorf(
+						"Verb expected after | at position %d.", i-1)
+				}
+			}
+			if len(vp) > 0 {
+				parts = append(parts, vp)
+			}
+		case c == '[':
+			flush()
+			opt, n, err := parseParts(s, i+1, true, ctx)
+			if err != nil {
+				return nil, i, err
+			}
+			i += n + 1
+			if i >= len(s) || s[i] != ']' {
+				return nil, i, fmt.Errorf(
+					"] expected but end of text reached.")
+			}
+			i++
+			parts = append(parts, newOptPart(opt...))
+		case c == ']':
+			if !nested {
+				return nil, i, fmt.Errorf(
+					"Unexpected ] at position %d.", i)
+			}
+			flush()
+			return parts, i - pos, nil
+		default:
+			text += string(c)
+			i++
+		}
+	}
+	flush()
+
+	return parts, i - pos, nil
+}
+
+// parseVerb parses %x or %{name|filter:arg|...} verb at pos. If the
+// verb is a bar, bar is returned with %{name|option:value|...} options
+// applied instead.
+func parseVerb(s string, pos int, ctx Context) (vb verb, bar *barPart,
+	read int, err error) {
+
+	s = s[pos:]
+	if len(s) < 2 || s[0] != '%' {
+		return verb{}, nil, 0, fmt.Errorf(
+			"Verb expected at position %d.", pos)
+	}
+	var opts []string
+	if s[1] == '{' {
+		i := strings.IndexByte(s, '}')
+		if i == -1 {
+			return verb{}, nil, 0, fmt.Errorf(
+				"} expected but end of text reached.")
+		}
+		pts := strings.Split(s[2:i], "|")
+		vb.key = pts[0]
+		opts = pts[1:]
+		read = i + 1
+		if vb.key == "" {
+			return verb{}, nil, 0, fmt.Errorf(
+				"Empty verb name at position %d.", pos)
+		}
+		for j := 0; j < len(vb.key); j++ {
+			if !isVerbChar(vb.key[j]) && vb.key[j] != '-' {
+				return verb{}, nil, 0, fmt.Errorf(
+					"Invalid verb name: %s.", vb.key)
+			}
+		}
+	} else if isVerbChar(s[1]) {
+		vb.key = s[1:2]
+		read = 2
+	} else {
+		return verb{}, nil, 0, fmt.Errorf(
+			"Verb expected at position %d.", pos)
+	}
+
+	if ctx != nil {
+		v, ok := ctx.lookup(vb.key)
+		if !ok {
+			return verb{}, nil, 0, fmt.Errorf(
+				"Unknown verb: %s.", s[:read])
+		}
+		vb.key = v.Name
+		if v.Bar {
+			bar = newBarPart(v.Name)
+			for _, o := range opts {
+				if err := bar.setOption(o); err != nil {
+					return verb{}, nil, 0, err
+				}
+			}
+			return verb{}, bar, read, nil
+		}
+	}
+	for _, o := range opts {
+		f, err := parseFilter(o)
+		if err != nil {
+			return verb{}, nil, 0, err
+		}
+		vb.filters = append(vb.filters, f)
+	}
+
+	return vb, nil, read, nil
+}
+
+func isVerbChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z'
+}
+
+// parseSubstModifiers splits width specification into the width itself,
+// truncation mode and priority, e.g. "-*%/m!2" or "20/e".
+func parseSubstModifiers(s string) (width string, mode byte, prio int,
+	err error) {
+
+	if i := strings.IndexByte(s, '!'); i != -1 {
+		prio, err = strconv.Atoi(s[i+1:])
+		if err != nil || prio < 0 {
+			return "", 0, 0, fmt.Errorf("Invalid priority: %s.",
+				s[i+1:])
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '/'); i != -1 {
+		m := s[i+1:]
+		if len(m) != 1 || strings.IndexByte(truncModes, m[0]) == -1 {
+			return "", 0, 0, fmt.Errorf(
+				"Invalid truncation mode: %s.", m)
 		}
+		mode = m[0]
+		s = s[:i]
 	}
 
-	return format, keys, nil
+	return s, mode, prio, nil
 }
 
 func parseSubstStarWidth(s string) (width int, percent bool, alignLeft bool,
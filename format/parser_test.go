@@ -34,7 +34,7 @@ func reprSlice(nodes []node) string {
 }
 
 func testParse(t *testing.T, s string, expected []node) {
-	actual, err := parse(s, 10)
+	actual, err := parse(s, 10, nil)
 	if err != nil {
 		t.Errorf("Error parsing string \"%s\". %s", s, err)
 	} else if !reflect.DeepEqual(expected, actual) {
@@ -54,35 +54,141 @@ func TestParse(t *testing.T) {
 	testParse(t, "foo {{bar}} baz",
 		[]node{textNode("foo {bar} baz")})
 	testParse(t, "{%a}",
-		[]node{newSubstNode(-1, false, false, "%s", []string{"a"})})
+		[]node{newSubstNode(-1, false, false, newVerbPart("a"))})
 	testParse(t, "{10:%a}",
-		[]node{newSubstNode(10, false, false, "%s", []string{"a"})})
+		[]node{newSubstNode(10, false, false, newVerbPart("a"))})
 	testParse(t, "{-10:%a}",
-		[]node{newSubstNode(10, false, true, "%s", []string{"a"})})
+		[]node{newSubstNode(10, false, true, newVerbPart("a"))})
 	testParse(t, "{-20%:%a}",
-		[]node{newSubstNode(10, true, true, "%s", []string{"a"})})
+		[]node{newSubstNode(10, true, true, newVerbPart("a"))})
 	testParse(t, "foo{30%:%a - %t}{-70%:%a}bar",
 		[]node{newTextNode("foo"),
-			newSubstNode(1, true, false, "%s - %s", []string{"a", "t"}),
-			newSubstNode(3, true, true, "%s", []string{"a"}),
+			newSubstNode(1, true, false, newVerbPart("a"),
+				textPart(" - "), newVerbPart("t")),
+			newSubstNode(3, true, true, newVerbPart("a")),
 			newTextNode("bar")})
 	testParse(t, "{10%:%a}{-90%:%t}",
-		[]node{newSubstNode(1, true, false, "%s", []string{"a"}),
-			newSubstNode(9, true, true, "%s", []string{"t"})})
+		[]node{newSubstNode(1, true, false, newVerbPart("a")),
+			newSubstNode(9, true, true, newVerbPart("t"))})
 	testParse(t, "{*%:%a}",
-		[]node{newSubstNode(10, true, false, "%s", []string{"a"})})
+		[]node{newSubstNode(10, true, false, newVerbPart("a"))})
 	testParse(t, "{-*%:%a}",
-		[]node{newSubstNode(10, true, true, "%s", []string{"a"})})
+		[]node{newSubstNode(10, true, true, newVerbPart("a"))})
 	testParse(t, "{*%:%a}{*%:%a}{*%:%a}",
-		[]node{newSubstNode(3, true, false, "%s", []string{"a"}),
-			newSubstNode(3, true, false, "%s", []string{"a"}),
-			newSubstNode(4, true, false, "%s", []string{"a"})})
+		[]node{newSubstNode(3, true, false, newVerbPart("a")),
+			newSubstNode(3, true, false, newVerbPart("a")),
+			newSubstNode(4, true, false, newVerbPart("a"))})
 	testParse(t, "{50%:%a}{*%:%a}{*%:%a}",
-		[]node{newSubstNode(5, true, false, "%s", []string{"a"}),
-			newSubstNode(2, true, false, "%s", []string{"a"}),
-			newSubstNode(3, true, false, "%s", []string{"a"})})
+		[]node{newSubstNode(5, true, false, newVerbPart("a")),
+			newSubstNode(2, true, false, newVerbPart("a")),
+			newSubstNode(3, true, false, newVerbPart("a"))})
 	testParse(t, "{-*%:%a}{50%:%a}{*%:%a}",
-		[]node{newSubstNode(2, true, true, "%s", []string{"a"}),
-			newSubstNode(5, true, false, "%s", []string{"a"}),
-			newSubstNode(3, true, false, "%s", []string{"a"})})
+		[]node{newSubstNode(2, true, true, newVerbPart("a")),
+			newSubstNode(5, true, false, newVerbPart("a")),
+			newSubstNode(3, true, false, newVerbPart("a"))})
+	testParse(t, "{[%a - ]%t}",
+		[]node{newSubstNode(-1, false, false,
+			newOptPart(newVerbPart("a"), textPart(" - ")),
+			newVerbPart("t"))})
+	testParse(t, "{%t|%f|%p}",
+		[]node{newSubstNode(-1, false, false,
+			newVerbPart("t", "f", "p"))})
+	testParse(t, "{[%b[ (%y)]: ]%t|%f}",
+		[]node{newSubstNode(-1, false, false,
+			newOptPart(newVerbPart("b"),
+				newOptPart(textPart(" ("), newVerbPart("y"),
+					textPart(")")),
+				textPart(": ")),
+			newVerbPart("t", "f"))})
+	testParse(t, "{%[%v%%%]|x}",
+		[]node{newSubstNode(-1, false, false, textPart("["),
+			newVerbPart("v"), textPart("%]|x"))})
+	testParse(t, "{$bold,cyan}{%a}{$reset}",
+		[]node{&styleNode{fg: "cyan", attrs: AttrBold, src: "bold,cyan"},
+			newSubstNode(-1, false, false, newVerbPart("a")),
+			&styleNode{reset: true, src: "reset"}})
+}
+
+func TestParseModifiers(t *testing.T) {
+	sn := func(width int, percent, alignLeft bool, trunc byte,
+		prio int, k string) node {
+
+		n := newSubstNode(width, percent, alignLeft,
+			newVerbPart(k)).(*substNode)
+		n.trunc = trunc
+		n.prio = prio
+		return n
+	}
+
+	testParse(t, "{5/e:%a}",
+		[]node{sn(5, false, false, truncEnd, 0, "a")})
+	testParse(t, "{-*%/m!2:%p}",
+		[]node{sn(10, true, true, truncMiddle, 2, "p")})
+	testParse(t, "{!1:%a}{-3/s:%t}",
+		[]node{sn(-1, false, false, truncCut, 1, "a"),
+			sn(3, false, true, truncStart, 0, "t")})
+	testParse(t, "{time: %a}",
+		[]node{newSubstNode(-1, false, false, textPart("time: "),
+			newVerbPart("a"))})
+	testParse(t, "twelve chars{*%:%t}",
+		[]node{textNode("twelve chars"),
+			sn(0, true, false, truncCut, 0, "t")})
+
+	for _, s := range []string{"{5/x:%a}", "{5/:%a}", "{5!:%a}",
+		"{5!-1:%a}", "{5/ee:%a}"} {
+		if err := Validate(s, nil); err == nil {
+			t.Errorf("Error expected parsing string \"%s\".", s)
+		}
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx := Context{{Short: 'a', Name: "artist"}, {Short: 't', Name: "title"},
+		{Name: "file-name"}}
+	testParse := func(s string, expected []node) {
+		actual, err := parse(s, 10, ctx)
+		if err != nil {
+			t.Errorf("Error parsing string \"%s\". %s", s, err)
+		} else if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Error parsing string \"%s\".\n"+
+				"Expected: %s\nActual: %s",
+				s, reprSlice(expected), reprSlice(actual))
+		}
+	}
+
+	testParse("{%a - %{title}}",
+		[]node{newSubstNode(-1, false, false, newVerbPart("artist"),
+			textPart(" - "), newVerbPart("title"))})
+	testParse("{[%{artist} - ]%t|%{file-name}}",
+		[]node{newSubstNode(-1, false, false,
+			newOptPart(newVerbPart("artist"), textPart(" - ")),
+			newVerbPart("title", "file-name"))})
+	testParse("{10:%{artist}}{%{title}}",
+		[]node{newSubstNode(10, false, false, newVerbPart("artist")),
+			newSubstNode(-1, false, false, newVerbPart("title"))})
+	testParse("{%{t|pad:2}|%{file-name|base|upper}}",
+		[]node{newSubstNode(-1, false, false, verbPart{
+			{key: "title", filters: []filter{{"pad", []string{"2"}}}},
+			{key: "file-name", filters: []filter{{"base", []string{}},
+				{"upper", []string{}}}},
+		})})
+
+	for _, s := range []string{"{%b}", "{%{album}}", "{%a|%{album}}",
+		"{%{file}}"} {
+		if err := Validate(s, ctx); err == nil {
+			t.Errorf("Error expected parsing string \"%s\".", s)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, s := range []string{"{[%a}", "{%a]}", "{%a|}", "{%a|b}",
+		"{[%a]]}", "{[[%a]}", "{%{}}", "{%{a b}}", "{%{artist}",
+		"{%{a|}}", "{%{a|foo}}", "{%{a|pad}}", "{%{a|pad:x}}",
+		"{%{a|pad:-1}}", "{%{a|upper:1}}", "{%{a|replace:x}}",
+		"}", "}x", "{%a}}", "{%a}}x"} {
+		if err := Validate(s, nil); err == nil {
+			t.Errorf("Error expected parsing string \"%s\".", s)
+		}
+	}
 }
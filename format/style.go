@@ -0,0 +1,164 @@
+// Copyright 2015 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of asp.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attr is a text attribute.
+type Attr uint
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrDim
+	AttrUnderline
+	AttrReverse
+	AttrBlink
+	AttrStandout
+)
+
+var attrNames = map[string]Attr{
+	"blink":     AttrBlink,
+	"bold":      AttrBold,
+	"dim":       AttrDim,
+	"reverse":   AttrReverse,
+	"standout":  AttrStandout,
+	"underline": AttrUnderline,
+}
+
+var colorNames = []string{"black", "blue", "cyan", "default", "green",
+	"magenta", "red", "white", "yellow"}
+
+// Style is a text style set with {$...} markup.
+type Style struct {
+	// Fg is a foreground color: basic color name, "default",
+	// 256-color palette index or #rrggbb value. Empty Fg means
+	// the color of the UI element the text is displayed in.
+	Fg    string
+	Attrs Attr
+}
+
+// Span is a part of the formatted text displayed with the same style.
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// styleNode is a {$...} markup node which sets style of the text which
+// follows it. It is a comma separated list of attributes and colors,
+// e.g. {$bold,cyan}. {$reset} resets the style to the default one.
+type styleNode struct {
+	reset bool
+	fg    string
+	attrs Attr
+	src   string
+}
+
+func newStyleNode(s string) (node, error) {
+	n := &styleNode{src: s}
+	for _, it := range strings.Split(s, ",") {
+		it = strings.TrimSpace(it)
+		if it == "reset" {
+			n.reset = true
+		} else if a, ok := attrNames[it]; ok {
+			n.attrs |= a
+		} else if isColor(it) {
+			n.fg = it
+		} else {
+			return nil, fmt.Errorf("Invalid style: %s.", it)
+		}
+	}
+
+	return n, nil
+}
+
+func isColor(s string) bool {
+	for _, c := range colorNames {
+		if s == c {
+			return true
+		}
+	}
+	if strings.HasPrefix(s, "#") {
+		_, err := strconv.ParseUint(s[1:], 16, 32)
+		return err == nil && len(s) == 7
+	}
+	i, err := strconv.Atoi(s)
+
+	return err == nil && i >= 0 && i <= 255
+}
+
+// apply returns style s modified by the node.
+func (n *styleNode) apply(s Style) Style {
+	if n.reset {
+		s = Style{}
+	}
+	if n.fg != "" {
+		s.Fg = n.fg
+	}
+	s.Attrs |= n.attrs
+
+	return s
+}
+
+func (n *styleNode) format(data map[string]string) string {
+	return ""
+}
+
+func (n *styleNode) repr() string {
+	return "{$" + n.src + "}"
+}
+
+// spans splits the formatted line into styled spans. ends are end
+// offsets of the nodes text in the line. Adjacent nodes with the same
+// style are joined into one span. Padding after the last node uses
+// the default style.
+func spans(nodes []node, line string, ends []int) []Span {
+	res := make([]Span, 0, 4)
+	var st Style
+	start := 0
+
+	for i, n := range nodes {
+		if sn, ok := n.(*styleNode); ok {
+			st = sn.apply(st)
+			continue
+		}
+		res = appendSpan(res, line, start, ends[i], st)
+		start = ends[i]
+	}
+
+	return appendSpan(res, line, start, len(line), Style{})
+}
+
+// appendSpan appends line[start:end] text to the spans joining it with
+// the last span if it has the same style.
+func appendSpan(res []Span, line string, start, end int, st Style) []Span {
+	if start == end {
+		return res
+	}
+	if l := len(res); l > 0 && res[l-1].Style == st {
+		// Spans are adjacent in the line.
+		res[l-1].Text = line[start-len(res[l-1].Text) : end]
+	} else {
+		res = append(res, Span{Text: line[start:end], Style: st})
+	}
+
+	return res
+}
@@ -8,3 +8,6 @@ require (
 	github.com/vchimishuk/config v0.0.0-20230910195755-ed7bd1b64558
 	github.com/vchimishuk/opt v0.0.0-20250103221129-d823c9050e21
 )
+
+// Fork which can be connected over Unix domain socket.
+replace github.com/vchimishuk/chubby => ./third_party/chubby
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
+)
+
+type helpItem struct {
+	data map[string]string
+	fmtr format.Formatter
+}
+
+func (i *helpItem) Format(width int) string {
+	return i.fmtr.Format(i.data, width)
+}
+
+func (i *helpItem) FormatSpans(width int) []format.Span {
+	return i.fmtr.FormatSpans(i.data, width)
+}
+
+func (i *helpItem) IsActive(val string) bool {
+	return false
+}
+
+// HelpWindow displays all key bindings.
+type HelpWindow struct {
+	list *ListWindow
+	fmtr format.Formatter
+}
+
+func NewHelpWindow(h, w, y, x int) (*HelpWindow, error) {
+	list, err := NewListWindow(h, w, y, x)
+	return &HelpWindow{
+		list: list,
+		fmtr: format.NewFormatter(config.FormatHelp, config.HelpVerbs),
+	}, err
+}
+
+func (w *HelpWindow) SetBindings(bindings []config.Binding) {
+	items := make([]ListItem, 0, len(bindings))
+	for _, b := range bindings {
+		items = append(items, &helpItem{
+			data: map[string]string{
+				"keys":    b.Keys.String(),
+				"command": b.Action.String(),
+			},
+			fmtr: w.fmtr,
+		})
+	}
+
+	w.list.Clear()
+	w.list.Add(items...)
+}
+
+func (w *HelpWindow) Search(text string) {
+	w.list.Search(text)
+}
+
+func (w *HelpWindow) SearchNext() {
+	w.list.SearchNext()
+}
+
+func (w *HelpWindow) SearchPrev() {
+	w.list.SearchPrev()
+}
+
+func (w *HelpWindow) Up() {
+	w.list.Up()
+}
+
+func (w *HelpWindow) Down() {
+	w.list.Down()
+}
+
+func (w *HelpWindow) PageUp() {
+	w.list.PageUp()
+}
+
+func (w *HelpWindow) PageDown() {
+	w.list.PageDown()
+}
+
+func (w *HelpWindow) Goto(i int) {
+	w.list.Goto(i)
+}
+
+func (w *HelpWindow) Home() {
+	w.list.Home()
+}
+
+func (w *HelpWindow) End() {
+	w.list.End()
+}
+
+func (w *HelpWindow) Tag() {
+	w.list.Tag()
+}
+
+func (w *HelpWindow) TagRange() {
+	w.list.TagRange()
+}
+
+func (w *HelpWindow) InvertTags() {
+	w.list.InvertTags()
+}
+
+func (w *HelpWindow) TagPattern(pattern string) error {
+	return w.list.TagPattern(pattern)
+}
+
+func (w *HelpWindow) ClearTags() {
+	w.list.ClearTags()
+}
+
+func (w *HelpWindow) SetHidden(hidden bool) {
+	w.list.SetHidden(hidden)
+}
+
+func (w *HelpWindow) Delete() {
+	w.list.Delete()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	ncurses "github.com/gbin/goncurses"
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/chubby"
+	ctime "github.com/vchimishuk/chubby/time"
+)
+
+// escTimeout is a time to wait for the key following Escape. Terminals
+// send Meta combinations as Escape immediately followed by the key.
+const escTimeout = 100 * time.Millisecond
+
+// keyReader collects pressed keys into bound key sequences. Key sequence
+// can be prefixed with a number (count) in vi style, e.g. "5j".
+type keyReader struct {
+	keys  config.KeySeq
+	count int
+	// Time the last key was pressed at.
+	last time.Time
+}
+
+// Feed processes the next pressed key. It returns action to execute
+// and its count (zero if no count was typed) once the whole bound key
+// sequence is typed. ok is false if more keys are expected or
+// the sequence is not bound to anything.
+func (r *keyReader) Feed(key ncurses.Key) (action config.Action, count int,
+	ok bool) {
+
+	r.last = time.Now()
+	if len(r.keys) == 0 && key >= '0' && key <= '9' {
+		a, prefix := config.KeyAction(config.KeySeq{key})
+		// Digit starts count only if it is not bound to anything,
+		// but it always continues already started count.
+		if r.count > 0 || (a.Cmd == config.CmdNoop && !prefix) {
+			r.count = r.count*10 + int(key-'0')
+			return config.Action{Cmd: config.CmdNoop}, 0, false
+		}
+	}
+
+	r.keys = append(r.keys, key)
+	a, prefix := config.KeyAction(r.keys)
+	if prefix {
+		return config.Action{Cmd: config.CmdNoop}, 0, false
+	}
+	count = r.count
+	r.Reset()
+
+	return a, count, a.Cmd != config.CmdNoop
+}
+
+// Timeout finishes pending key sequence if no keys were pressed for
+// the config.KeyTimeout. Sequence which is bound and is a prefix of
+// longer one at the same time is executed on timeout.
+func (r *keyReader) Timeout() (action config.Action, count int, ok bool) {
+	// Escape starts Meta combination if the next key follows it
+	// immediately, bare Escape cancels the sequence.
+	if n := len(r.keys); n > 0 && r.keys[n-1] == KEY_ESC &&
+		time.Since(r.last) >= escTimeout {
+
+		r.Reset()
+		return config.Action{Cmd: config.CmdNoop}, 0, false
+	}
+	if !r.Pending() || time.Since(r.last) < config.KeyTimeout {
+		return config.Action{Cmd: config.CmdNoop}, 0, false
+	}
+
+	a := config.Action{Cmd: config.CmdNoop}
+	if len(r.keys) > 0 {
+		a, _ = config.KeyAction(r.keys)
+	}
+	count = r.count
+	r.Reset()
+
+	return a, count, a.Cmd != config.CmdNoop
+}
+
+// Pending returns true if some keys are typed but not executed yet.
+func (r *keyReader) Pending() bool {
+	return len(r.keys) > 0 || r.count > 0
+}
+
+func (r *keyReader) Reset() {
+	r.keys = nil
+	r.count = 0
+}
+
+// String returns keys typed so far.
+func (r *keyReader) String() string {
+	s := ""
+	if r.count > 0 {
+		s = strconv.Itoa(r.count)
+	}
+
+	return s + r.keys.String()
+}
+
+// execCount executes action count times. Zero count executes action once.
+// Some commands treat count in a special way: home and end move cursor
+// to the count-th item, seek and volume commands multiply their step,
+// next and prev switch count tracks with a single server request.
+// Count is ignored by commands which make no sense to repeat, e.g.
+// the ones prompting for input.
+func execCount(a config.Action, count int) error {
+	if count == 0 {
+		return execAction(a)
+	}
+
+	switch a.Cmd {
+	case config.CmdHome, config.CmdEnd:
+		currentView().Goto(count - 1)
+
+		return nil
+	case config.CmdSeekBackward, config.CmdSeekForward:
+		t := ctime.New(defSeekStep)
+		if len(a.Args) > 0 {
+			t, _ = config.ParseTime(a.Args[0])
+		}
+		a.Args = []string{strconv.Itoa(int(t) * count)}
+
+		return execAction(a)
+	case config.CmdVolumeDown, config.CmdVolumeUp:
+		step := defVolumeStep
+		if len(a.Args) > 0 {
+			step, _ = config.ParseStep(a.Args[0])
+		}
+		a.Args = []string{strconv.Itoa(step * count)}
+
+		return execAction(a)
+	case config.CmdNext, config.CmdPrev:
+		// Single request for all the switches, large counts must
+		// not flood the request queue.
+		name, f := "next", (*chubby.Chubby).Next
+		if a.Cmd == config.CmdPrev {
+			name, f = "prev", (*chubby.Chubby).Prev
+		}
+		submit(name, func(c *chubby.Chubby) error {
+			for i := 0; i < count; i++ {
+				if err := f(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil)
+
+		return nil
+	case config.CmdDown, config.CmdUp, config.CmdPageDown,
+		config.CmdPageUp, config.CmdSearchNext, config.CmdSearchPrev,
+		config.CmdTag:
+
+		for i := 0; i < count; i++ {
+			err := execAction(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return execAction(a)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	ncurses "github.com/gbin/goncurses"
+	"github.com/vchimishuk/asp/config"
+)
+
+func feedKeys(r *keyReader, keys ...ncurses.Key) (config.Action, int, bool) {
+	var a config.Action
+	var count int
+	var ok bool
+	for _, k := range keys {
+		a, count, ok = r.Feed(k)
+	}
+
+	return a, count, ok
+}
+
+func TestKeyReaderMeta(t *testing.T) {
+	config.Bind(config.KeySeq{KEY_ESC, 'x'}, config.Action{Cmd: config.CmdStop})
+	config.Bind(config.KeySeq{'g', KEY_ESC, 'x'},
+		config.Action{Cmd: config.CmdPause})
+
+	r := &keyReader{}
+	a, count, ok := feedKeys(r, '5', KEY_ESC, 'x')
+	if !ok || a.Cmd != config.CmdStop || count != 5 {
+		t.Errorf("Stop with count 5 expected, got %s, %d, %t",
+			a, count, ok)
+	}
+	a, _, ok = feedKeys(r, 'g', KEY_ESC, 'x')
+	if !ok || a.Cmd != config.CmdPause {
+		t.Errorf("Pause expected, got %s, %t", a, ok)
+	}
+}
+
+func TestKeyReaderEscape(t *testing.T) {
+	config.KeyTimeout = time.Second
+	config.Bind(config.KeySeq{KEY_ESC, 'x'}, config.Action{Cmd: config.CmdStop})
+
+	r := &keyReader{}
+	feedKeys(r, '5', KEY_ESC)
+	if _, _, ok := r.Timeout(); ok || !r.Pending() {
+		t.Errorf("Escape expected to wait for the next key")
+	}
+	r.last = time.Now().Add(-escTimeout)
+	if a, _, ok := r.Timeout(); ok || r.Pending() {
+		t.Errorf("Bare escape expected to cancel, got %s, %t", a, ok)
+	}
+}
+
+func TestKeyReader(t *testing.T) {
+	config.KeyTimeout = time.Second
+	config.Bind(config.KeySeq{'j'}, config.Action{Cmd: config.CmdDown})
+	config.Bind(config.KeySeq{'G'}, config.Action{Cmd: config.CmdEnd})
+	config.Bind(config.KeySeq{'g', 'g'}, config.Action{Cmd: config.CmdHome})
+
+	tests := []struct {
+		keys    string
+		expired bool
+		cmd     config.Cmd
+		count   int
+		ok      bool
+	}{
+		{"j", false, config.CmdDown, 0, true},
+		{"5j", false, config.CmdDown, 5, true},
+		{"10G", false, config.CmdEnd, 10, true},
+		{"gg", false, config.CmdHome, 0, true},
+		{"3gg", false, config.CmdHome, 3, true},
+		{"g", false, config.CmdNoop, 0, false},
+		{"g", true, config.CmdNoop, 0, false},
+		{"x", false, config.CmdNoop, 0, false},
+	}
+	for _, test := range tests {
+		r := &keyReader{}
+		var keys []ncurses.Key
+		for _, c := range test.keys {
+			keys = append(keys, ncurses.Key(c))
+		}
+		a, count, ok := feedKeys(r, keys...)
+		if test.expired {
+			r.last = time.Now().Add(-config.KeyTimeout)
+			a, count, ok = r.Timeout()
+		}
+		if a.Cmd != test.cmd || count != test.count || ok != test.ok {
+			t.Errorf("Keys \"%s\" mismatch.\nExpected: %s, %d, %t\n"+
+				"Actual: %s, %d, %t", test.keys, test.cmd,
+				test.count, test.ok, a.Cmd, count, ok)
+		}
+		// Only unfinished sequence is left pending.
+		pending := test.keys == "g" && !test.expired
+		if r.Pending() != pending {
+			t.Errorf("Keys \"%s\" pending expected %t", test.keys,
+				pending)
+		}
+	}
+}
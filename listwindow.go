@@ -2,14 +2,17 @@ package main
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	ncurses "github.com/gbin/goncurses"
 	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
 )
 
 type ListItem interface {
 	Format(width int) string
+	FormatSpans(width int) []format.Span
 	IsActive(val string) bool
 }
 
@@ -23,16 +26,25 @@ type ListWindow struct {
 	// manipulated by user with keyboard.
 	cursor     int
 	searchText string
+	// Indexes of tagged (selected) items.
+	tagged map[int]bool
+	// Index of the last tagged item. Used to tag ranges.
+	tagAnchor int
+	// Hidden window is not drawn on the screen. It is used when
+	// several windows share the same screen area.
+	hidden bool
 }
 
 func NewListWindow(h, w, y, x int) (*ListWindow, error) {
 	window, err := ncurses.NewWindow(h, w, y, x)
 	return &ListWindow{
-		window: window,
-		items:  nil,
-		offset: -1,
-		active: "",
-		cursor: -1,
+		window:    window,
+		items:     nil,
+		offset:    -1,
+		active:    "",
+		cursor:    -1,
+		tagged:    make(map[int]bool),
+		tagAnchor: -1,
 	}, err
 }
 
@@ -44,6 +56,8 @@ func (w *ListWindow) Clear() {
 	w.items = nil
 	w.offset = -1
 	w.cursor = -1
+	w.tagged = make(map[int]bool)
+	w.tagAnchor = -1
 
 	w.refresh()
 }
@@ -59,6 +73,17 @@ func (w *ListWindow) Add(items ...ListItem) {
 	w.refresh()
 }
 
+func (w *ListWindow) Hidden() bool {
+	return w.hidden
+}
+
+func (w *ListWindow) SetHidden(hidden bool) {
+	w.hidden = hidden
+	if !hidden {
+		w.refresh()
+	}
+}
+
 func (w *ListWindow) Active() string {
 	return w.active
 }
@@ -104,6 +129,14 @@ func (w *ListWindow) SetCursor(i int) error {
 	return nil
 }
 
+// Goto moves cursor to the i-th item or to the last one if there are
+// not enough items.
+func (w *ListWindow) Goto(i int) {
+	if len(w.items) > 0 {
+		w.SetCursor(max(0, min(i, len(w.items)-1)))
+	}
+}
+
 func (w *ListWindow) Down() {
 	if len(w.items) > 0 && w.cursor < len(w.items)-1 {
 		w.cursor++
@@ -170,6 +203,90 @@ func (w *ListWindow) End() {
 	}
 }
 
+// Tag toggles tag of the item under the cursor and moves cursor down.
+func (w *ListWindow) Tag() {
+	if w.cursor == -1 {
+		return
+	}
+
+	w.toggleTag(w.cursor)
+	w.tagAnchor = w.cursor
+	if w.cursor < len(w.items)-1 {
+		w.Down()
+	} else {
+		w.refresh()
+	}
+}
+
+// TagRange tags all items between the last tagged item and the cursor.
+func (w *ListWindow) TagRange() {
+	if w.cursor == -1 || w.tagAnchor == -1 {
+		return
+	}
+
+	for i := min(w.cursor, w.tagAnchor); i <= max(w.cursor, w.tagAnchor); i++ {
+		w.tagged[i] = true
+	}
+	w.tagAnchor = w.cursor
+	w.refresh()
+}
+
+func (w *ListWindow) InvertTags() {
+	for i := range w.items {
+		w.toggleTag(i)
+	}
+	w.refresh()
+}
+
+// TagPattern tags all items which match given shell pattern. Pattern is
+// matched against the formatted item text, case insensitively.
+func (w *ListWindow) TagPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	pattern = "*" + strings.ToLower(pattern) + "*"
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	_, width := w.window.MaxYX()
+	for i, it := range w.items {
+		s := strings.ToLower(it.Format(width))
+		if ok, _ := path.Match(pattern, s); ok {
+			w.tagged[i] = true
+		}
+	}
+	w.refresh()
+
+	return nil
+}
+
+func (w *ListWindow) ClearTags() {
+	w.tagged = make(map[int]bool)
+	w.tagAnchor = -1
+	w.refresh()
+}
+
+// Tagged returns all tagged items in the list order.
+func (w *ListWindow) Tagged() []ListItem {
+	var items []ListItem
+	for i, it := range w.items {
+		if w.tagged[i] {
+			items = append(items, it)
+		}
+	}
+
+	return items
+}
+
+func (w *ListWindow) toggleTag(i int) {
+	if w.tagged[i] {
+		delete(w.tagged, i)
+	} else {
+		w.tagged[i] = true
+	}
+}
+
 // TODO: Search starting from the current position not the first item.
 func (w *ListWindow) Search(text string) {
 	if text != "" {
@@ -231,37 +348,44 @@ func (w *ListWindow) height() int {
 }
 
 func (w *ListWindow) refresh() {
+	if w.hidden {
+		return
+	}
+
 	height, width := w.window.MaxYX()
 	l := len(w.items)
 
 	for i := 0; i < height; i++ {
 		var attr ncurses.Char
-		var s string
+		var spans []format.Span
 		ii := w.offset + i
 
 		if w.offset == -1 || ii > l-1 {
 			attr = config.ColorNormal
-			s = strings.Repeat(" ", width)
+			spans = []format.Span{{Text: strings.Repeat(" ", width)}}
 		} else {
 			attr = config.ColorList
 			sel := w.items[ii].IsActive(w.active)
 
-			if sel || ii == w.cursor {
-
-				if sel && ii == w.cursor {
-					attr = config.ColorCursorActive
-				} else if sel {
-					attr = config.ColorListActive
-				} else { // ii == w.cursor
-					attr = config.ColorCursor
-				}
+			if sel && ii == w.cursor {
+				attr = config.ColorCursorActive
+			} else if ii == w.cursor {
+				attr = config.ColorCursor
+			} else if w.tagged[ii] {
+				attr = config.ColorTagged
+			} else if sel {
+				attr = config.ColorListActive
 			}
-			s = w.items[ii].Format(width)
+			spans = w.items[ii].FormatSpans(width)
 		}
 
-		w.window.AttrOn(attr)
-		w.window.MovePrint(i, 0, s)
-		w.window.AttrOff(attr)
+		w.window.Move(i, 0)
+		for _, sp := range spans {
+			a := config.StyleAttr(attr, sp.Style)
+			w.window.AttrOn(a)
+			w.window.Print(sp.Text)
+			w.window.AttrOff(a)
+		}
 	}
 
 	w.window.Refresh()
@@ -4,15 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"path"
 	"strconv"
-	"sync"
-	"syscall"
+	"strings"
 	"time"
 
 	ncurses "github.com/gbin/goncurses"
 	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
 	"github.com/vchimishuk/chubby"
 	ctime "github.com/vchimishuk/chubby/time"
 	"github.com/vchimishuk/opt"
@@ -22,34 +21,84 @@ const Version = "0.1.0"
 
 // TODO: Add args into usage.
 var Options = []*opt.Desc{
-	{"h", "host", opt.ArgString, "HOST",
-		"server host name"},
-	{"", "help", opt.ArgNone, "",
-		"display this help"},
-	{"p", "port", opt.ArgString, "PORT",
-		"server port"},
-	{"v", "version", opt.ArgNone,
-		"", "output version information and exit"},
+	{Short: "", Long: "check-config", Arg: opt.ArgNone, ArgName: "",
+		Description: "check configuration file and exit"},
+	{Short: "h", Long: "host", Arg: opt.ArgString, ArgName: "ADDR",
+		Description: "server address: HOST[:PORT] or unix:PATH"},
+	{Short: "", Long: "help", Arg: opt.ArgNone, ArgName: "",
+		Description: "display this help"},
+	{Short: "", Long: "print-default-config", Arg: opt.ArgNone, ArgName: "",
+		Description: "output default configuration file and exit"},
+	{Short: "p", Long: "port", Arg: opt.ArgString, ArgName: "PORT",
+		Description: "server port"},
+	{Short: "s", Long: "server", Arg: opt.ArgString, ArgName: "NAME",
+		Description: "server profile name"},
+	{Short: "v", Long: "version", Arg: opt.ArgNone, ArgName: "",
+		Description: "output version information and exit"},
 }
 
-var NcursesMu sync.Mutex
+// errQuit is returned by quit command to stop the application.
+var errQuit = errors.New("quit")
+
+// Default seek and volume change steps.
+const (
+	defSeekStep   = 5
+	defVolumeStep = 1
+)
 
 var (
 	rootWnd        *ncurses.Window
 	titleWnd       *TitleWindow
 	statusWnd      *StatusWindow
 	browserWnd     *BrowserWindow
+	playlistWnd    *PlaylistWindow
+	playlistsWnd   *PlaylistsWindow
+	helpWnd        *HelpWindow
 	cmdWnd         *CommandWindow
 	msgWnd         *MessageWindow
 	msgWndHideTime time.Time
 )
 
 var (
-	chub           *chubby.Chubby
-	eventsDone     <-chan any
+	chub *chubby.Chubby
+	// Server profile of the current connection.
+	server         *config.Server
 	activePath     string
 	browserPath    string
 	browserEntries []chubby.Entry
+	// Directory the last play command was issued for.
+	playPath string
+)
+
+// view is a window displayed in the main area of the screen.
+type view int
+
+const (
+	viewBrowser view = iota
+	viewPlaylist
+	viewPlaylists
+	viewHelp
+)
+
+var curView view = viewBrowser
+
+// Chub protocol does not provide a way to fetch tracks of a playlist, so
+// we reconstruct it from the VFS directory the playing track comes from.
+// Name and length of the server playlist we have loaded or are loading
+// tracks for are stored to detect the playlist change. They are set when
+// the load is submitted, so failed load is not retried until the playlist
+// changes.
+var (
+	playlistOutdated bool
+	playlistName     string
+	playlistLength   int
+	playlistRoot     string
+	playlistTracks   []*chubby.Track
+)
+
+var (
+	playlistsOutdated bool
+	playlists         []*chubby.Playlist
 )
 
 var (
@@ -75,6 +124,17 @@ func main() {
 		printVersion()
 		os.Exit(0)
 	}
+	if opts.Has("print-default-config") {
+		fmt.Print(config.Default())
+		os.Exit(0)
+	}
+	if opts.Has("check-config") {
+		if err := config.Check(); err != nil {
+			printErr(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	err = doMain(opts, args)
 	if err != nil {
 		printErr(err)
@@ -83,6 +143,11 @@ func main() {
 }
 
 func doMain(opts opt.Options, args []string) error {
+	// Validate configuration before ncurses takes over the terminal,
+	// so errors are printed in a readable way.
+	if err := config.Check(); err != nil {
+		return fmt.Errorf("failed to load configuration file: %w", err)
+	}
 	if err := initNcurses(); err != nil {
 		return fmt.Errorf("failed to initalize ncurses: %w", err)
 	}
@@ -96,212 +161,336 @@ func doMain(opts opt.Options, args []string) error {
 		return fmt.Errorf("failed to initalize UI: %w", err)
 	}
 
-	host, port, err := hostPort(opts)
+	var err error
+	server, err = serverProfile(opts)
 	if err != nil {
 		return err
 	}
 
-	var eventsDone <-chan any
-	chub = &chubby.Chubby{}
-	eventsDone, err = reconnect(chub, host, port)
+	cn, err := connect(server, startPath(server))
 	if err != nil {
 		return fmt.Errorf("server connection error: %w", err)
 	}
+	setChub(cn.client)
+	cn.use()
+	// Connection lost later is restored in background.
+	supervisorDone := make(chan any, 1)
+	go supervise(cn, server, uiQuit, supervisorDone)
+	// Server requests are executed in background, so slow server
+	// never blocks UI.
+	go serveRequests()
+	go tick()
+
+	keys := &keyReader{}
+	// Configuration reload is performed by the UI goroutine, so keymap
+	// is never changed in the middle of a key sequence processing.
+	go handleSignals(func() {
+		keys.Reset()
+		if err := reloadConfig(); err != nil {
+			showMessage("%s", err)
+		}
+	})
+
+	for {
+		var err error
+		var cerr *commandError
+		var a config.Action
+		var count int
+		var ok bool
+
+		ch := rootWnd.GetChar()
+		// Resize is handled with SIGWINCH.
+		if ch != 0 && ch != ncurses.KEY_RESIZE {
+			a, count, ok = keys.Feed(ncurses.Key(ch))
+		} else {
+			a, count, ok = keys.Timeout()
+		}
+		processEvents()
+		cmdWnd.ShowKeys(keys.String())
+		if ok {
+			err = execCount(a, count)
+		}
+		if errors.Is(err, errQuit) {
+			break
+		} else if errors.As(err, &cerr) {
+			showMessage("%s", cerr)
+		}
+		if playlistOutdated {
+			loadPlaylist()
+		}
+		if playlistsOutdated {
+			loadPlaylists()
+		}
 
-	NcursesMu.Lock()
-	browserPath, err = config.LoadPath()
-	if browserPath == "" || err != nil {
-		browserPath = "/"
+		updatePending()
+		hideMessage(false)
 	}
 
-	browserEntries, err = chub.List(browserPath)
+	close(uiQuit)
+	// Request queue can be busy with a hung server, do not wait
+	// for it longer than a second.
+	closed := make(chan any, 1)
+	go func() {
+		chubMu.Lock()
+		chub.Close()
+		chubMu.Unlock()
+		closed <- struct{}{}
+	}()
+
+	err = config.SavePath(server.Name, browserPath)
 	if err != nil {
-		return fmt.Errorf("server error: %w", err)
+		return fmt.Errorf("failed to save current path: %w", err)
 	}
-	updateWindows()
-	NcursesMu.Unlock()
 
-	sigs := make(chan os.Signal)
-	signal.Notify(sigs, syscall.SIGWINCH)
-	go func() {
-		for {
-			<-sigs
+	wait(closed, time.Second)
+	wait(supervisorDone, time.Second)
 
-			// TODO: Asp fails on resize if command window is active.
-			NcursesMu.Lock()
-			destroyNcurses()
-			if err := initNcurses(); err != nil {
-				printErr(fmt.Errorf("failed to re-initalize ncurses: %w",
-					err))
-				os.Exit(1)
+	return nil
+}
+
+// execAction executes command with its arguments. Arguments are
+// expected to be validated by the config.ParseAction already.
+func execAction(a config.Action) error {
+	var err error
 
+	switch a.Cmd {
+	case config.CmdApply:
+		if curView == viewPlaylist {
+			t := playlistWnd.Cursor()
+			if t != nil {
+				play(t.Path, false)
 			}
-			if err := initUI(); err != nil {
-				printErr(fmt.Errorf("failed to re-initalize UI: %w", err))
-				os.Exit(1)
+		} else if curView == viewBrowser {
+			entry := browserWnd.Cursor()
+			if entry.IsDir() {
+				chdir(entry.Dir().Path, nil)
+			} else {
+				play(entry.Track().Path, false)
 			}
-			updateWindows()
-			NcursesMu.Unlock()
 		}
-	}()
-
-inputLoop:
-	for {
-		var err error
-		ch := rootWnd.GetChar()
-		if ch != 0 {
-			key := ncurses.Key(ch)
-			cmd := config.Command(key)
-
-			switch cmd {
-			case config.CmdApply:
-				entry := browserWnd.Cursor()
-				if entry.IsDir() {
-					err = chdir(entry.Dir().Path)
-				} else {
-					err = chub.Play(entry.Track().Path)
-				}
-			case config.CmdBack:
-				err = chdir(path.Dir(browserPath))
-			case config.CmdEnd:
-				NcursesMu.Lock()
-				browserWnd.End()
-				NcursesMu.Unlock()
-			case config.CmdDown:
-				NcursesMu.Lock()
-				browserWnd.Down()
-				NcursesMu.Unlock()
-			case config.CmdHome:
-				NcursesMu.Lock()
-				browserWnd.Home()
-				NcursesMu.Unlock()
-			case config.CmdPause:
-				NcursesMu.Lock()
-				err = chub.Pause()
-				NcursesMu.Unlock()
-			case config.CmdPlay:
-				entry := browserWnd.Cursor()
-				var p string
-				if entry.IsDir() {
-					p = entry.Dir().Path
+	case config.CmdBack:
+		if curView == viewBrowser {
+			chdir(path.Dir(browserPath), nil)
+		}
+	case config.CmdBind:
+		key, _ := config.ParseKey(a.Args[0])
+		action, _ := config.ParseAction(strings.Join(a.Args[1:], " "))
+		config.Bind(key, action)
+	case config.CmdCd:
+		chdir(resolvePath(a.Args[0]), nil)
+	case config.CmdClearTags:
+		currentView().ClearTags()
+	case config.CmdCommand:
+		hideMessage(true)
+		line := cmdWnd.Input(":", completeCommand)
+		err = runCommand(line)
+	case config.CmdCopyPath:
+		err = copyToClipboard(strings.Join(selectedPaths(),
+			"\n"))
+		if err != nil {
+			showMessage("failed to copy to clipboard")
+			err = nil
+		}
+	case config.CmdCreatePlaylist:
+		hideMessage(true)
+		name := cmdWnd.Input("Create playlist: ", nil)
+		if name != "" {
+			submit("create playlist", func(c *chubby.Chubby) error {
+				return c.CreatePlaylist(name)
+			}, nil)
+		}
+	case config.CmdDeletePlaylist:
+		if curView == viewPlaylists {
+			pls := playlistsWnd.Tagged()
+			if len(pls) == 0 && playlistsWnd.Cursor() != nil {
+				pls = append(pls, playlistsWnd.Cursor())
+			}
+			if len(pls) > 0 {
+				var prompt string
+				if len(pls) == 1 {
+					prompt = "Delete playlist " +
+						pls[0].Name + "? (y/n) "
 				} else {
-					p = entry.Track().Path
+					prompt = "Delete " +
+						strconv.Itoa(len(pls)) +
+						" playlists? (y/n) "
 				}
-				err = chub.Play(p)
-			case config.CmdKill:
-				err = chub.Kill()
-			case config.CmdPageDown:
-				NcursesMu.Lock()
-				browserWnd.PageDown()
-				NcursesMu.Unlock()
-			case config.CmdPageUp:
-				NcursesMu.Lock()
-				browserWnd.PageUp()
-				NcursesMu.Unlock()
-			case config.CmdSearch:
-				// Hide message window first in case it is active.
 				hideMessage(true)
-				text := cmdWnd.Input("Search:")
-				NcursesMu.Lock()
-				browserWnd.Search(text)
-				NcursesMu.Unlock()
-			case config.CmdShowActive:
-				if activePath != "" {
-					err = chdir(path.Dir(activePath))
-					NcursesMu.Lock()
-					browserWnd.ShowActive()
-					NcursesMu.Unlock()
+				if cmdWnd.Input(prompt, nil) == "y" {
+					submit("delete playlist", func(c *chubby.Chubby) error {
+						for _, pl := range pls {
+							err := c.DeletePlaylist(pl.Name)
+							if err != nil {
+								return err
+							}
+						}
+						return nil
+					}, nil)
 				}
-			case config.CmdSearchNext:
-				NcursesMu.Lock()
-				browserWnd.SearchNext()
-				NcursesMu.Unlock()
-			case config.CmdSearchPrev:
-				NcursesMu.Lock()
-				browserWnd.SearchPrev()
-				NcursesMu.Unlock()
-			case config.CmdSeekBackward:
-				NcursesMu.Lock()
-				err = chub.Seek(ctime.New(5),
-					chubby.SeekModeBackward)
-				NcursesMu.Unlock()
-			case config.CmdSeekForward:
-				NcursesMu.Lock()
-				err = chub.Seek(ctime.New(5),
-					chubby.SeekModeForward)
-				NcursesMu.Unlock()
-			case config.CmdStop:
-				err = chub.Stop()
-			case config.CmdUp:
-				NcursesMu.Lock()
-				browserWnd.Up()
-				NcursesMu.Unlock()
-			case config.CmdVolumeDown:
-				err = chub.Volume(-1, chubby.VolumeModeRel)
-			case config.CmdVolumeUp:
-				err = chub.Volume(1, chubby.VolumeModeRel)
-			case config.CmdQuit:
-				break inputLoop
 			}
 		}
-		if err != nil {
-			if chubby.IsServerError(err) {
-				showMessage("server error received")
+	case config.CmdEnd:
+		currentView().End()
+	case config.CmdDown:
+		currentView().Down()
+	case config.CmdHelp:
+		helpWnd.SetBindings(config.Bindings())
+		toggleView(viewHelp)
+	case config.CmdHome:
+		currentView().Home()
+	case config.CmdPause:
+		submit("pause", (*chubby.Chubby).Pause, nil)
+	case config.CmdPlay:
+		if curView == viewPlaylist {
+			ts := playlistWnd.Tagged()
+			t := playlistWnd.Cursor()
+			if len(ts) == 1 {
+				t = ts[0]
+			}
+			if len(ts) > 1 {
+				showMessage("only one item can be played")
+			} else if t != nil {
+				play(t.Path, false)
+			}
+		} else if curView == viewBrowser {
+			es := browserWnd.Tagged()
+			entry := browserWnd.Cursor()
+			if len(es) == 1 {
+				entry = es[0]
+			}
+			if len(es) > 1 {
+				showMessage("only one item can be played")
+			} else if entry.IsDir() {
+				play(entry.Dir().Path, true)
 			} else {
-				// Network related error. Close connection,
-				// connection attempt will be performed lower.
-				chub.Close()
+				play(entry.Track().Path, false)
 			}
 		}
-		if !chub.Connected() {
-			eventsDone, err = reconnect(chub, host, port)
-			if err != nil {
-				showMessage("server connection error")
-			} else {
+	case config.CmdPrev:
+		submit("prev", (*chubby.Chubby).Prev, nil)
+	case config.CmdPlaylist:
+		toggleView(viewPlaylist)
+	case config.CmdPlaylists:
+		toggleView(viewPlaylists)
+	case config.CmdRenamePlaylist:
+		if curView == viewPlaylists {
+			pl := playlistsWnd.Cursor()
+			if pl != nil {
 				hideMessage(true)
+				name := cmdWnd.Input("Rename playlist "+
+					pl.Name+" to: ", nil)
+				if name != "" {
+					submit("rename playlist",
+						func(c *chubby.Chubby) error {
+							return c.RenamePlaylist(
+								pl.Name, name)
+						},
+						// Server does not notify about
+						// renamed playlists.
+						loadPlaylists)
+				}
 			}
 		}
-
-		hideMessage(false)
-	}
-
-	chub.Close()
-
-	err = config.SavePath(browserPath)
-	if err != nil {
-		return fmt.Errorf("failed to save current path: %w", err)
-	}
-
-	if eventsDone != nil {
-		wait(eventsDone, time.Second)
-	}
-
-	return nil
-}
-
-func reconnect(chub *chubby.Chubby, host string, port int) (<-chan any, error) {
-	err := chub.Connect(host, port)
-	if err != nil {
-		return nil, err
-	}
-
-	events, err := chub.Events(true)
-	if err != nil {
-		chub.Close()
-		return nil, err
-	}
-
-	chubStatus, err = chub.Status()
-	if err != nil {
-		chub.Close()
-		return nil, err
+	case config.CmdInvertTags:
+		currentView().InvertTags()
+	case config.CmdKill:
+		submit("kill", (*chubby.Chubby).Kill, nil)
+	case config.CmdNext:
+		submit("next", (*chubby.Chubby).Next, nil)
+	case config.CmdPageDown:
+		currentView().PageDown()
+	case config.CmdPageUp:
+		currentView().PageUp()
+	case config.CmdSearch:
+		// Hide message window first in case it is active.
+		hideMessage(true)
+		text := cmdWnd.Input("Search: ", nil)
+		currentView().Search(text)
+	case config.CmdShowActive:
+		if curView == viewPlaylist {
+			playlistWnd.ShowActive()
+		} else if curView == viewPlaylists {
+			playlistsWnd.ShowActive()
+		} else if activePath != "" {
+			chdir(path.Dir(activePath), func() {
+				browserWnd.ShowActive()
+			})
+		}
+	case config.CmdSearchNext:
+		currentView().SearchNext()
+	case config.CmdSearchPrev:
+		currentView().SearchPrev()
+	case config.CmdSeek:
+		err = seek(a.Args[0])
+	case config.CmdSeekBackward:
+		t := ctime.New(defSeekStep)
+		if len(a.Args) > 0 {
+			t, _ = config.ParseTime(a.Args[0])
+		}
+		submit("seek", func(c *chubby.Chubby) error {
+			return c.Seek(t, chubby.SeekModeBackward)
+		}, nil)
+	case config.CmdSeekForward:
+		t := ctime.New(defSeekStep)
+		if len(a.Args) > 0 {
+			t, _ = config.ParseTime(a.Args[0])
+		}
+		submit("seek", func(c *chubby.Chubby) error {
+			return c.Seek(t, chubby.SeekModeForward)
+		}, nil)
+	case config.CmdServer:
+		if len(config.Servers) == 0 {
+			return newCommandError("server: no servers configured")
+		}
+		var name string
+		if len(a.Args) > 0 {
+			name = a.Args[0]
+		} else {
+			hideMessage(true)
+			name = cmdWnd.Input("Server: ", func(s string) []string {
+				return completeName(s, config.ServerNames())
+			})
+		}
+		if name != "" {
+			err = switchServer(name)
+		}
+	case config.CmdStop:
+		submit("stop", (*chubby.Chubby).Stop, nil)
+	case config.CmdTag:
+		currentView().Tag()
+	case config.CmdTagPattern:
+		hideMessage(true)
+		text := cmdWnd.Input("Tag: ", nil)
+		perr := currentView().TagPattern(text)
+		if perr != nil {
+			showMessage("invalid pattern")
+		}
+	case config.CmdTagRange:
+		currentView().TagRange()
+	case config.CmdUp:
+		currentView().Up()
+	case config.CmdVolume:
+		v, mode, _ := config.ParseVolume(a.Args[0])
+		setVolume(v, mode)
+	case config.CmdVolumeDown:
+		step := defVolumeStep
+		if len(a.Args) > 0 {
+			step, _ = config.ParseStep(a.Args[0])
+		}
+		setVolume(-step, chubby.VolumeModeRel)
+	case config.CmdVolumeUp:
+		step := defVolumeStep
+		if len(a.Args) > 0 {
+			step, _ = config.ParseStep(a.Args[0])
+		}
+		setVolume(step, chubby.VolumeModeRel)
+	case config.CmdQuit:
+		return errQuit
+	case config.CmdReload:
+		err = reloadConfig()
 	}
 
-	chubStarted = time.Now().Unix() - int64(chubStatus.TrackPos)
-	done := make(chan any, 1)
-	go handleEvents(events, done)
-
-	return done, nil
+	return err
 }
 
 func initNcurses() error {
@@ -316,7 +505,7 @@ func initNcurses() error {
 	if err := ncurses.StartColor(); err != nil {
 		return err
 	}
-	rootWnd.Timeout(1000)
+	rootWnd.Timeout(int(pollTimeout.Milliseconds()))
 
 	ncurses.Echo(false)
 	ncurses.CBreak(true)
@@ -337,6 +526,15 @@ func destroyNcurses() {
 	if browserWnd != nil {
 		browserWnd.Delete()
 	}
+	if playlistWnd != nil {
+		playlistWnd.Delete()
+	}
+	if playlistsWnd != nil {
+		playlistsWnd.Delete()
+	}
+	if helpWnd != nil {
+		helpWnd.Delete()
+	}
 	if statusWnd != nil {
 		statusWnd.Delete()
 	}
@@ -363,6 +561,22 @@ func initUI() error {
 	if err != nil {
 		return err
 	}
+	// Playlist window shares the same spot with the browser window.
+	playlistWnd, err = NewPlaylistWindow(h-3, w, 1, 0)
+	if err != nil {
+		return err
+	}
+	// Server playlists window.
+	playlistsWnd, err = NewPlaylistsWindow(h-3, w, 1, 0)
+	if err != nil {
+		return err
+	}
+	// Key bindings help window.
+	helpWnd, err = NewHelpWindow(h-3, w, 1, 0)
+	if err != nil {
+		return err
+	}
+	updateVisibility()
 	// Current paying status window.
 	statusWnd, err = NewStatusWindow(w, h-2, 0)
 	if err != nil {
@@ -386,12 +600,174 @@ func initUI() error {
 	return nil
 }
 
+// resetUI re-initializes ncurses and re-creates all windows, so they
+// pick up new terminal size and the current configuration.
+func resetUI() {
+	// Command window object is kept, so the input in progress
+	// survives the reset.
+	cw := cmdWnd
+	destroyNcurses()
+	if err := initNcurses(); err != nil {
+		printErr(fmt.Errorf("failed to re-initalize ncurses: %w", err))
+		os.Exit(1)
+	}
+	if err := initUI(); err != nil {
+		printErr(fmt.Errorf("failed to re-initalize UI: %w", err))
+		os.Exit(1)
+	}
+	cw.Replace(cmdWnd)
+	cmdWnd = cw
+	updateWindows()
+}
+
+// reloadConfig re-reads configuration file and applies new colors,
+// formats and key bindings. Reloaded server profiles are available to
+// the server command, but the current connection is kept.
+func reloadConfig() error {
+	err := config.Load()
+	if err == nil {
+		resetUI()
+	}
+	if err != nil {
+		return newCommandError("config: %s", err)
+	}
+	showMessage("configuration reloaded")
+
+	return nil
+}
+
 func updateWindows() {
 	browserWnd.SetDir(browserPath, browserEntries)
+	playlistWnd.SetPlaylist(playlistName, playlistTracks)
+	playlistsWnd.SetPlaylists(playlists)
+	helpWnd.SetBindings(config.Bindings())
+	browserWnd.SetActive(activePath)
+	playlistWnd.SetActive(activePath)
 	updateStatus()
 	// TODO: Update message window.
 }
 
+// listView is a window which can be displayed in the main area of
+// the screen.
+type listView interface {
+	Up()
+	Down()
+	PageUp()
+	PageDown()
+	Home()
+	End()
+	Goto(i int)
+	Search(text string)
+	SearchNext()
+	SearchPrev()
+	Tag()
+	TagRange()
+	InvertTags()
+	TagPattern(pattern string) error
+	ClearTags()
+}
+
+func currentView() listView {
+	switch curView {
+	case viewPlaylist:
+		return playlistWnd
+	case viewPlaylists:
+		return playlistsWnd
+	case viewHelp:
+		return helpWnd
+	default:
+		return browserWnd
+	}
+}
+
+// selectedPaths returns paths of all tagged items of the current view or
+// path of the item under the cursor if there are no tagged items.
+func selectedPaths() []string {
+	var ps []string
+
+	switch curView {
+	case viewBrowser:
+		es := browserWnd.Tagged()
+		if len(es) == 0 {
+			es = append(es, browserWnd.Cursor())
+		}
+		for _, e := range es {
+			if e.IsDir() {
+				ps = append(ps, e.Dir().Path)
+			} else {
+				ps = append(ps, e.Track().Path)
+			}
+		}
+	case viewPlaylist:
+		ts := playlistWnd.Tagged()
+		if len(ts) == 0 && playlistWnd.Cursor() != nil {
+			ts = append(ts, playlistWnd.Cursor())
+		}
+		for _, t := range ts {
+			ps = append(ps, t.Path)
+		}
+	case viewPlaylists:
+		pls := playlistsWnd.Tagged()
+		if len(pls) == 0 && playlistsWnd.Cursor() != nil {
+			pls = append(pls, playlistsWnd.Cursor())
+		}
+		for _, pl := range pls {
+			ps = append(ps, pl.Name)
+		}
+	}
+
+	return ps
+}
+
+// toggleView shows the given view or returns back to the browser if
+// the view is already displayed.
+func toggleView(v view) {
+	if curView == v {
+		curView = viewBrowser
+	} else {
+		curView = v
+	}
+	updateVisibility()
+}
+
+func updateVisibility() {
+	// Hide first, so the visible window is drawn the last one.
+	if curView != viewBrowser {
+		browserWnd.SetHidden(true)
+	}
+	if curView != viewPlaylist {
+		playlistWnd.SetHidden(true)
+	}
+	if curView != viewPlaylists {
+		playlistsWnd.SetHidden(true)
+	}
+	if curView != viewHelp {
+		helpWnd.SetHidden(true)
+	}
+	switch curView {
+	case viewBrowser:
+		browserWnd.SetHidden(false)
+	case viewPlaylist:
+		playlistWnd.SetHidden(false)
+	case viewPlaylists:
+		playlistsWnd.SetHidden(false)
+	case viewHelp:
+		helpWnd.SetHidden(false)
+	}
+}
+
+// trackElapsed returns number of seconds the current track has been
+// played for at the given moment. Elapsed time runs from the started
+// timestamp only while playing, otherwise the server reported position
+// is kept.
+func trackElapsed(st *chubby.Status, started int64, now int64) int64 {
+	if st.State != chubby.StatePlaying {
+		return int64(st.TrackPos)
+	}
+
+	return now - started
+}
+
 func updateStatus() {
 	if chubStatus == nil {
 		statusWnd.Update(chubby.StateStopped, nil)
@@ -399,54 +775,207 @@ func updateStatus() {
 	}
 
 	data := make(map[string]string)
-	data["v"] = strconv.Itoa(chubStatus.Volume)
+	data["volume"] = strconv.Itoa(chubStatus.Volume)
+	data["volume-bar"] = data["volume"]
 
 	track := chubStatus.Track
 	if track != nil {
-		data["a"] = track.Artist
-		data["b"] = track.Album
-		data["t"] = track.Title
-		data["n"] = strconv.Itoa(track.Number)
-		data["l"] = track.Length.String()
-		data["o"] = ctime.Time(time.Now().Unix() - chubStarted).String()
-		// "r": strconv.Itoa(plist.Length),
-		// "q": strconv.Itoa(se.PlistPos),
+		elapsed := trackElapsed(chubStatus, chubStarted,
+			time.Now().Unix())
+		data["artist"] = track.Artist
+		data["album"] = track.Album
+		data["title"] = track.Title
+		data["track"] = strconv.Itoa(track.Number)
+		data["length"] = track.Length.String()
+		data["elapsed"] = ctime.Time(elapsed).String()
+		data["remaining"] = ctime.Time(max(int(track.Length)-
+			int(elapsed), 0)).String()
+		if track.Length > 0 {
+			data["bar"] = strconv.FormatInt(
+				elapsed*100/int64(track.Length), 10)
+			data["percent"] = data["bar"]
+		}
+		if chubStatus.State == chubby.StatePlaying {
+			end := time.Now().Add(time.Duration(max(
+				int(track.Length)-int(elapsed), 0)) * time.Second)
+			data["end"] = format.Clock(config.ClockFormat, end)
+		}
+		data["path"] = track.Path
+		data["filename"] = path.Base(track.Path)
+	}
+	if plist := chubStatus.Playlist; plist != nil {
+		data["tracks"] = strconv.Itoa(plist.Length)
+		// Server counts tracks from zero.
+		data["position"] = strconv.Itoa(chubStatus.PlaylistPos + 1)
 	}
 
 	if track == nil {
 		activePath = ""
 		browserWnd.SetActive(activePath)
+		playlistWnd.SetActive(activePath)
 	} else if activePath != track.Path {
 		activePath = track.Path
 		browserWnd.SetActive(activePath)
+		playlistWnd.SetActive(activePath)
+	}
+
+	plist := chubStatus.Playlist
+	if plist != nil {
+		playlistsWnd.SetActive(plist.Name)
+	} else {
+		playlistsWnd.SetActive("")
+	}
+	if plist != nil && track != nil {
+		playlistOutdated = plist.Name != playlistName ||
+			plist.Length != playlistLength ||
+			!isParent(track.Path, playlistRoot)
+	}
+	data["dir"] = browserPath
+	var dirLen ctime.Time
+	dirTracks := 0
+	for _, e := range browserEntries {
+		if !e.IsDir() {
+			dirLen += e.Track().Length
+			dirTracks++
+		}
 	}
-	data["p"] = browserPath
+	data["dir-length"] = dirLen.String()
+	data["dir-tracks"] = strconv.Itoa(dirTracks)
+	data["clock"] = format.Clock(config.ClockFormat, time.Now())
 
 	titleWnd.Update(data)
-	statusWnd.Update(chubStatus.State, data)
+	if retryTime.IsZero() {
+		statusWnd.Update(chubStatus.State, data)
+	} else if d := time.Until(retryTime).Round(time.Second); d > 0 {
+		statusWnd.SetText(fmt.Sprintf("disconnected — retrying in %ds",
+			int(d.Seconds())))
+	} else {
+		statusWnd.SetText("disconnected — reconnecting")
+	}
 	// Call to restore cursor on command window in case it is active.
 	cmdWnd.Refresh()
 }
 
-func chdir(p string) error {
-	es, err := chub.List(p)
-	if err != nil {
+func play(p string, dir bool) {
+	submit("play", func(c *chubby.Chubby) error {
+		return c.Play(p)
+	}, func() {
+		if dir {
+			playPath = p
+		} else {
+			playPath = path.Dir(p)
+		}
+	})
+}
+
+func setVolume(v int, mode chubby.VolumeMode) {
+	submit("volume", func(c *chubby.Chubby) error {
+		return c.Volume(v, mode)
+	}, nil)
+}
+
+// loadPlaylist fetches tracks of the currently playing playlist.
+func loadPlaylist() {
+	st := chubStatus
+	playlistOutdated = false
+
+	if st == nil || st.Playlist == nil || st.Track == nil {
+		return
+	}
+
+	root := playPath
+	if root == "" || !isParent(st.Track.Path, root) {
+		root = path.Dir(st.Track.Path)
+	}
+	name := st.Playlist.Name
+	length := st.Playlist.Length
+	playlistName = name
+	playlistLength = length
+	playlistRoot = root
+
+	var tracks []*chubby.Track
+	submitTimeout("load playlist", listTimeout, func(c *chubby.Chubby) error {
+		var err error
+		tracks, err = listTracks(c, root)
 		return err
+	}, func() {
+		// Another playlist load was started meanwhile.
+		if playlistName != name || playlistLength != length ||
+			playlistRoot != root {
+
+			return
+		}
+		// Playlist is rebuilt from its directory, which could be
+		// changed after the playlist was created.
+		if len(tracks) != length {
+			showMessage("playlist %s does not match %s", name, root)
+			tracks = nil
+		}
+		playlistTracks = tracks
+		playlistWnd.SetPlaylist(playlistName, playlistTracks)
+	})
+}
+
+// loadPlaylists fetches all playlists available on the server.
+func loadPlaylists() {
+	playlistsOutdated = false
+
+	var pls []*chubby.Playlist
+	submit("load playlists", func(c *chubby.Chubby) error {
+		var err error
+		pls, err = c.Playlists()
+		return err
+	}, func() {
+		playlists = pls
+		playlistsWnd.SetPlaylists(playlists)
+	})
+}
+
+// listTracks returns all tracks of the given directory and its
+// subdirectories.
+func listTracks(c *chubby.Chubby, p string) ([]*chubby.Track, error) {
+	es, err := c.List(p)
+	if err != nil {
+		return nil, err
 	}
 
-	NcursesMu.Lock()
-	browserPath = p
-	browserEntries = es
-	updateWindows()
-	NcursesMu.Unlock()
+	var tracks []*chubby.Track
+	for _, e := range es {
+		if e.IsDir() {
+			ts, err := listTracks(c, e.Dir().Path)
+			if err != nil {
+				return nil, err
+			}
+			tracks = append(tracks, ts...)
+		} else {
+			tracks = append(tracks, e.Track())
+		}
+	}
 
-	return nil
+	return tracks, nil
+}
+
+// chdir changes browser directory. then is called after the directory
+// is changed if not nil.
+func chdir(p string, then func()) {
+	var es []chubby.Entry
+	submit("cd", func(c *chubby.Chubby) error {
+		var err error
+		es, err = c.List(p)
+		return err
+	}, func() {
+		browserPath = p
+		browserEntries = es
+		updateWindows()
+		if then != nil {
+			then()
+		}
+	})
 }
 
 func showMessage(format string, args ...any) {
-	NcursesMu.Lock()
-	defer NcursesMu.Unlock()
 	msgWnd.Update(format, args...)
+	spinnerShown = false
 
 	delay := time.Second * 3
 	msgWndHideTime = time.Now().Add(delay)
@@ -455,67 +984,12 @@ func showMessage(format string, args ...any) {
 func hideMessage(force bool) {
 	if msgWndHideTime.Unix() != 0 &&
 		(force || msgWndHideTime.Before(time.Now())) {
-		NcursesMu.Lock()
-		defer NcursesMu.Unlock()
 
 		msgWnd.Clear()
 		msgWndHideTime = time.UnixMilli(0)
 	}
 }
 
-func handleEvents(events <-chan chubby.Event, done chan<- any) {
-	var ticker *time.Ticker
-
-loop:
-	for {
-		NcursesMu.Lock()
-		updateStatus()
-		NcursesMu.Unlock()
-
-		state := chubStatus.State
-		if state == chubby.StatePlaying && ticker == nil {
-			ticker = time.NewTicker(time.Millisecond * 900)
-		} else if state != chubby.StatePlaying && ticker != nil {
-			ticker.Stop()
-			ticker = nil
-		}
-
-		var tickerCh <-chan time.Time
-		if ticker != nil {
-			tickerCh = ticker.C
-		}
-
-		select {
-		case e, ok := <-events:
-			if !ok {
-				break loop
-			}
-			if se, ok := e.(*chubby.StatusEvent); ok {
-				NcursesMu.Lock()
-				chubStatus = &chubby.Status{
-					State:       se.State,
-					Volume:      se.Volume,
-					PlaylistPos: se.PlaylistPos,
-					TrackPos:    se.TrackPos,
-					Playlist:    se.Playlist,
-					Track:       se.Track,
-				}
-
-				chubStarted = time.Now().Unix() -
-					int64(se.TrackPos)
-				NcursesMu.Unlock()
-			}
-		case <-tickerCh:
-		}
-	}
-
-	if ticker != nil {
-		ticker.Stop()
-	}
-
-	done <- struct{}{}
-}
-
 func wait(ch <-chan any, delay time.Duration) {
 	t := time.NewTicker(delay)
 	select {
@@ -524,31 +998,98 @@ func wait(ch <-chan any, delay time.Duration) {
 	}
 }
 
-func hostPort(opts opt.Options) (string, int, error) {
-	var host string = config.ChubHost
-	var port string = strconv.Itoa(config.ChubPort)
-	var err error
+// serverProfile returns the server to connect to on start. It is
+// the profile given with --server option or server property, or
+// chub-host and chub-port properties if there are none. Host and port
+// can be overridden with options, and with environment variables unless
+// the server is given with --server option.
+func serverProfile(opts opt.Options) (*config.Server, error) {
+	srv := config.Server{
+		Host: config.ChubHost,
+		Port: config.ChubPort,
+		Path: "/",
+	}
+	name := config.DefaultServer
+	if n, ok := opts.String("server"); ok {
+		name = n
+	}
+	if name != "" {
+		s := config.FindServer(name)
+		if s == nil {
+			return nil, fmt.Errorf("unknown server %s", name)
+		}
+		srv = *s
+	}
 
-	if h := os.Getenv("ASP_HOST"); h != "" {
-		host = h
+	_, named := opts.String("server")
+	if h := os.Getenv("ASP_HOST"); h != "" && !named {
+		srv.Host = h
 	}
 	if h, ok := opts.String("host"); ok {
-		host = h
+		srv.Host = h
 	}
 
-	if p := os.Getenv("ASP_PORT"); p != "" {
+	port := strconv.Itoa(srv.Port)
+	if p := os.Getenv("ASP_PORT"); p != "" && !named {
 		port = p
 	}
 	if p, ok := opts.String("port"); ok {
 		port = p
 	}
-
 	iport, err := strconv.Atoi(port)
 	if err != nil {
-		return "", 0, fmt.Errorf("invalid port %s", port)
+		return nil, fmt.Errorf("invalid port %s", port)
 	}
+	srv.Port = iport
+	if _, err := srv.Addr(); err != nil {
+		return nil, err
+	}
+
+	return &srv, nil
+}
 
-	return host, iport, err
+// startPath returns the directory to start browsing the server from.
+// It is the last path saved for the server or the profile's one.
+func startPath(srv *config.Server) string {
+	dir, err := config.LoadPath(srv.Name)
+	if dir == "" || err != nil {
+		dir = srv.Path
+	}
+	if dir == "" {
+		dir = "/"
+	}
+
+	return dir
+}
+
+// switchServer saves the current path and connects to the server
+// profile with the given name in background.
+func switchServer(name string) error {
+	srv := config.FindServer(name)
+	if srv == nil {
+		return newCommandError("server: unknown server %s", name)
+	}
+	if err := config.SavePath(server.Name, browserPath); err != nil {
+		showMessage("failed to save current path: %s", err)
+	}
+
+	server = srv
+	// Old server data is not valid anymore, it is refreshed once
+	// the connection is established. Status is kept till then not
+	// to blank the status bar.
+	activePath = ""
+	browserPath = startPath(srv)
+	browserEntries = nil
+	playPath = ""
+	playlistName = ""
+	playlistLength = 0
+	playlistRoot = ""
+	playlistTracks = nil
+	playlists = nil
+	updateWindows()
+	reconnect(srv)
+
+	return nil
 }
 
 func printErr(err error) {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/chubby"
+	ctime "github.com/vchimishuk/chubby/time"
+	"github.com/vchimishuk/opt"
+)
+
+func TestTrackElapsed(t *testing.T) {
+	tests := []struct {
+		state    chubby.State
+		pos      int
+		now      int64
+		expected int64
+	}{
+		{chubby.StatePlaying, 10, 100, 10},
+		{chubby.StatePlaying, 10, 130, 40},
+		{chubby.StatePaused, 10, 100, 10},
+		{chubby.StatePaused, 10, 130, 10},
+		{chubby.StateStopped, 0, 130, 0},
+	}
+	// Track started at 90 and 10 seconds were played when the status
+	// was received at 100.
+	const started = 90
+	for _, test := range tests {
+		st := &chubby.Status{State: test.state,
+			TrackPos: ctime.New(test.pos)}
+		actual := trackElapsed(st, started, test.now)
+		if actual != test.expected {
+			t.Errorf("Elapsed mismatch for %s at %d.\n"+
+				"Expected: %d\nActual: %d", test.state, test.now,
+				test.expected, actual)
+		}
+	}
+}
+
+func TestServerProfile(t *testing.T) {
+	config.ChubHost = "localhost"
+	config.ChubPort = 5115
+	config.Servers = []*config.Server{
+		{Name: "kitchen", Host: "kitchen.local", Port: 6000, Path: "/"},
+	}
+	t.Setenv("ASP_HOST", "env.local")
+	t.Setenv("ASP_PORT", "7000")
+
+	tests := []struct {
+		args []string
+		host string
+		port int
+	}{
+		{[]string{}, "env.local", 7000},
+		{[]string{"-s", "kitchen"}, "kitchen.local", 6000},
+		{[]string{"-s", "kitchen", "-p", "8000"}, "kitchen.local", 8000},
+		{[]string{"-h", "opt.local"}, "opt.local", 7000},
+	}
+	for _, test := range tests {
+		opts, _, err := opt.Parse(test.args, Options)
+		if err != nil {
+			t.Fatalf("Error parsing options %v. %s", test.args, err)
+		}
+		srv, err := serverProfile(opts)
+		if err != nil {
+			t.Errorf("Error for options %v. %s", test.args, err)
+		} else if srv.Host != test.host || srv.Port != test.port {
+			t.Errorf("Server mismatch for options %v.\n"+
+				"Expected: %s:%d\nActual: %s:%d", test.args,
+				test.host, test.port, srv.Host, srv.Port)
+		}
+	}
+}
@@ -2,14 +2,16 @@ package main
 
 import (
 	"strings"
-	"unicode/utf8"
 
 	ncurses "github.com/gbin/goncurses"
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
 )
 
 type PanelWindow struct {
 	window *ncurses.Window
-	text   string
+	color  ncurses.Char
+	spans  []format.Span
 }
 
 func NewPanelWindow(w, y, x int) (*PanelWindow, error) {
@@ -22,11 +24,17 @@ func NewPanelWindow(w, y, x int) (*PanelWindow, error) {
 }
 
 func (w *PanelWindow) SetColor(color ncurses.Char) {
+	w.color = color
 	w.window.SetBackground(color)
 }
 
 func (w *PanelWindow) SetText(text string) {
-	w.text = text
+	w.SetSpans([]format.Span{{Text: text}})
+}
+
+// SetSpans displays text styled with format markup.
+func (w *PanelWindow) SetSpans(spans []format.Span) {
+	w.spans = spans
 	w.refresh()
 }
 
@@ -36,8 +44,15 @@ func (w *PanelWindow) Clear() {
 }
 
 func (w *PanelWindow) refresh() {
-	w.window.MovePrint(0, 0, w.text)
-	l := utf8.RuneCountInString(w.text)
+	w.window.Move(0, 0)
+	l := 0
+	for _, sp := range w.spans {
+		a := config.StyleAttr(w.color, sp.Style)
+		w.window.AttrOn(a)
+		w.window.Print(sp.Text)
+		w.window.AttrOff(a)
+		l += format.Width(sp.Text)
+	}
 	if l < w.Width() {
 		w.window.MovePrint(0, l, strings.Repeat(" ", w.Width()-l))
 	}
@@ -0,0 +1,179 @@
+package main
+
+// TODO: Switch to the playlist under the cursor and add browser entries
+//       (tagged ones too) to a playlist. Chub protocol has no commands
+//       for that yet, so chubby can not provide them.
+
+import (
+	"strconv"
+
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
+	"github.com/vchimishuk/chubby"
+)
+
+type playlistItem struct {
+	plist *chubby.Playlist
+	data  map[string]string
+	fmtr  format.Formatter
+}
+
+func newPlaylistItem(plist *chubby.Playlist,
+	fmtr format.Formatter) *playlistItem {
+
+	data := map[string]string{
+		"name":   plist.Name,
+		"length": plist.Duration.String(),
+		"tracks": strconv.Itoa(plist.Length),
+	}
+
+	return &playlistItem{plist, data, fmtr}
+}
+
+func (i *playlistItem) Format(width int) string {
+	return i.fmtr.Format(i.data, width)
+}
+
+func (i *playlistItem) FormatSpans(width int) []format.Span {
+	return i.fmtr.FormatSpans(i.data, width)
+}
+
+func (i *playlistItem) IsActive(val string) bool {
+	return i.plist.Name == val
+}
+
+// PlaylistsWindow displays all playlists available on the server.
+type PlaylistsWindow struct {
+	list *ListWindow
+	fmtr format.Formatter
+}
+
+func NewPlaylistsWindow(h, w, y, x int) (*PlaylistsWindow, error) {
+	list, err := NewListWindow(h, w, y, x)
+	return &PlaylistsWindow{
+		list: list,
+		fmtr: format.NewFormatter(config.FormatPlaylists,
+			config.PlaylistsVerbs),
+	}, err
+}
+
+func (w *PlaylistsWindow) SetPlaylists(plists []*chubby.Playlist) {
+	items := make([]ListItem, 0, len(plists))
+	for _, p := range plists {
+		items = append(items, newPlaylistItem(p, w.fmtr))
+	}
+
+	var name string
+	if c := w.Cursor(); c != nil {
+		name = c.Name
+	}
+
+	w.list.Clear()
+	w.list.Add(items...)
+
+	// Try to keep cursor on the same playlist.
+	for i, p := range plists {
+		if p.Name == name {
+			w.list.SetCursor(i)
+			break
+		}
+	}
+}
+
+// Cursor returns playlist under the cursor or nil if there are
+// no playlists.
+func (w *PlaylistsWindow) Cursor() *chubby.Playlist {
+	it := w.list.Cursor()
+	if it == nil {
+		return nil
+	}
+
+	return it.(*playlistItem).plist
+}
+
+// SetActive highlights playlist which is being played.
+func (w *PlaylistsWindow) SetActive(name string) {
+	w.list.SetActive(name)
+}
+
+func (w *PlaylistsWindow) ShowActive() {
+	w.list.ShowActive()
+}
+
+func (w *PlaylistsWindow) Search(text string) {
+	w.list.Search(text)
+}
+
+func (w *PlaylistsWindow) SearchNext() {
+	w.list.SearchNext()
+}
+
+func (w *PlaylistsWindow) SearchPrev() {
+	w.list.SearchPrev()
+}
+
+func (w *PlaylistsWindow) Up() {
+	w.list.Up()
+}
+
+func (w *PlaylistsWindow) Down() {
+	w.list.Down()
+}
+
+func (w *PlaylistsWindow) PageUp() {
+	w.list.PageUp()
+}
+
+func (w *PlaylistsWindow) PageDown() {
+	w.list.PageDown()
+}
+
+func (w *PlaylistsWindow) Goto(i int) {
+	w.list.Goto(i)
+}
+
+func (w *PlaylistsWindow) Home() {
+	w.list.Home()
+}
+
+func (w *PlaylistsWindow) End() {
+	w.list.End()
+}
+
+// Tagged returns all tagged playlists.
+func (w *PlaylistsWindow) Tagged() []*chubby.Playlist {
+	var pls []*chubby.Playlist
+	for _, it := range w.list.Tagged() {
+		pls = append(pls, it.(*playlistItem).plist)
+	}
+
+	return pls
+}
+
+func (w *PlaylistsWindow) Tag() {
+	w.list.Tag()
+}
+
+func (w *PlaylistsWindow) TagRange() {
+	w.list.TagRange()
+}
+
+func (w *PlaylistsWindow) InvertTags() {
+	w.list.InvertTags()
+}
+
+func (w *PlaylistsWindow) TagPattern(pattern string) error {
+	return w.list.TagPattern(pattern)
+}
+
+func (w *PlaylistsWindow) ClearTags() {
+	w.list.ClearTags()
+}
+
+func (w *PlaylistsWindow) SetHidden(hidden bool) {
+	w.list.SetHidden(hidden)
+}
+
+func (w *PlaylistsWindow) Delete() {
+	w.list.Delete()
+}
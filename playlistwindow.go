@@ -0,0 +1,149 @@
+package main
+
+import (
+	"github.com/vchimishuk/asp/config"
+	"github.com/vchimishuk/asp/format"
+	"github.com/vchimishuk/chubby"
+)
+
+// PlaylistWindow displays tracks of the playlist is being played by
+// the server.
+type PlaylistWindow struct {
+	name   string
+	list   *ListWindow
+	tracks []*chubby.Track
+	fmtr   format.Formatter
+}
+
+func NewPlaylistWindow(h, w, y, x int) (*PlaylistWindow, error) {
+	list, err := NewListWindow(h, w, y, x)
+	return &PlaylistWindow{
+		name:   "",
+		list:   list,
+		tracks: nil,
+		fmtr: format.NewFormatter(config.FormatPlaylistTrack,
+			config.TrackVerbs),
+	}, err
+}
+
+func (w *PlaylistWindow) Name() string {
+	return w.name
+}
+
+func (w *PlaylistWindow) Tracks() []*chubby.Track {
+	return w.tracks
+}
+
+func (w *PlaylistWindow) SetPlaylist(name string, tracks []*chubby.Track) {
+	items := make([]ListItem, 0, len(tracks))
+	for _, t := range tracks {
+		items = append(items, newItem(t, trackData(t), w.fmtr))
+	}
+
+	w.name = name
+	w.tracks = tracks
+	w.list.Clear()
+	w.list.Add(items...)
+	w.list.ShowActive()
+}
+
+// Cursor returns track under the cursor or nil if playlist is empty.
+func (w *PlaylistWindow) Cursor() *chubby.Track {
+	it := w.list.Cursor()
+	if it == nil {
+		return nil
+	}
+
+	return it.(*item).entry.Track()
+}
+
+// SetActive highlights currently playing track. Cursor follows
+// the active track every time it is changed.
+func (w *PlaylistWindow) SetActive(path string) {
+	if w.list.Active() != path {
+		w.list.SetActive(path)
+		w.list.ShowActive()
+	}
+}
+
+func (w *PlaylistWindow) ShowActive() {
+	w.list.ShowActive()
+}
+
+func (w *PlaylistWindow) Search(text string) {
+	w.list.Search(text)
+}
+
+func (w *PlaylistWindow) SearchNext() {
+	w.list.SearchNext()
+}
+
+func (w *PlaylistWindow) SearchPrev() {
+	w.list.SearchPrev()
+}
+
+func (w *PlaylistWindow) Up() {
+	w.list.Up()
+}
+
+func (w *PlaylistWindow) Down() {
+	w.list.Down()
+}
+
+func (w *PlaylistWindow) PageUp() {
+	w.list.PageUp()
+}
+
+func (w *PlaylistWindow) PageDown() {
+	w.list.PageDown()
+}
+
+func (w *PlaylistWindow) Goto(i int) {
+	w.list.Goto(i)
+}
+
+func (w *PlaylistWindow) Home() {
+	w.list.Home()
+}
+
+func (w *PlaylistWindow) End() {
+	w.list.End()
+}
+
+// Tagged returns all tagged tracks.
+func (w *PlaylistWindow) Tagged() []*chubby.Track {
+	var ts []*chubby.Track
+	for _, it := range w.list.Tagged() {
+		ts = append(ts, it.(*item).entry.Track())
+	}
+
+	return ts
+}
+
+func (w *PlaylistWindow) Tag() {
+	w.list.Tag()
+}
+
+func (w *PlaylistWindow) TagRange() {
+	w.list.TagRange()
+}
+
+func (w *PlaylistWindow) InvertTags() {
+	w.list.InvertTags()
+}
+
+func (w *PlaylistWindow) TagPattern(pattern string) error {
+	return w.list.TagPattern(pattern)
+}
+
+func (w *PlaylistWindow) ClearTags() {
+	w.list.ClearTags()
+}
+
+func (w *PlaylistWindow) SetHidden(hidden bool) {
+	w.list.SetHidden(hidden)
+}
+
+func (w *PlaylistWindow) Delete() {
+	w.list.Delete()
+}
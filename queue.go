@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/vchimishuk/chubby"
+)
+
+// Server request timeouts.
+const (
+	requestTimeout = 5 * time.Second
+	// listTimeout is used for recursive directory listings which
+	// can take a while on large collections.
+	listTimeout = 30 * time.Second
+)
+
+// pendingDelay is a time after which the pending request indicator
+// is shown.
+const pendingDelay = 300 * time.Millisecond
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+var (
+	errTimeout = errors.New("server timeout")
+	// errBusy is returned if the request queue is full.
+	errBusy = errors.New("server is busy")
+)
+
+// request is a server call executed by the request queue. Call runs
+// in the queue goroutine and must not touch UI, it is done's job.
+type request struct {
+	// name is displayed by the pending indicator and error messages.
+	name    string
+	timeout time.Duration
+	call    func(c *chubby.Chubby) error
+	// done is called by the UI goroutine if the call succeeds.
+	done    func()
+	started time.Time
+	// result receives the call result of the sync request instead
+	// of the UI goroutine.
+	result chan error
+}
+
+var (
+	// requests is never waited on by the UI goroutine, otherwise it
+	// can deadlock with the queue goroutine waiting on uiEvents.
+	requests = make(chan *request, 64)
+	// pending contains submitted but not completed requests.
+	// Accessed by the UI goroutine only.
+	pending []*request
+	// spinnerShown is true if the pending indicator is displayed
+	// in the message window. Accessed by the UI goroutine only.
+	spinnerShown bool
+)
+
+// submit queues the server call. done is called by the UI goroutine
+// after the call succeeds, errors are reported with a message.
+func submit(name string, call func(c *chubby.Chubby) error, done func()) {
+	submitTimeout(name, requestTimeout, call, done)
+}
+
+func submitTimeout(name string, timeout time.Duration,
+	call func(c *chubby.Chubby) error, done func()) {
+
+	r := &request{
+		name:    name,
+		timeout: timeout,
+		call:    call,
+		done:    done,
+		started: time.Now(),
+	}
+	if !enqueue(r) {
+		showMessage("%s: %s", name, errBusy)
+		return
+	}
+	pending = append(pending, r)
+}
+
+// enqueue adds the request to the queue. False is returned if the queue
+// is full.
+func enqueue(r *request) bool {
+	select {
+	case requests <- r:
+		return true
+	default:
+		return false
+	}
+}
+
+// callSync executes the server call and waits for the result. It is
+// used when the UI goroutine can not continue without the result, e.g.
+// for completion. UI events are processed while waiting.
+func callSync(call func(c *chubby.Chubby) error) error {
+	r := &request{
+		timeout: requestTimeout,
+		call:    call,
+		started: time.Now(),
+		result:  make(chan error, 1),
+	}
+	if !enqueue(r) {
+		return errBusy
+	}
+
+	for {
+		select {
+		case err := <-r.result:
+			return err
+		case e := <-uiEvents:
+			e()
+		}
+	}
+}
+
+// serveRequests executes queued requests one by one.
+func serveRequests() {
+	for r := range requests {
+		// Loop variable is shared between iterations before Go 1.22.
+		r := r
+		err := execRequest(r)
+		if r.result != nil {
+			r.result <- err
+			continue
+		}
+		post(func() {
+			completeRequest(r, err)
+		})
+	}
+}
+
+// execRequest executes the request call. Connection is closed on
+// network errors and timeouts, so supervisor reconnects in background.
+func execRequest(r *request) error {
+	chubMu.Lock()
+	defer chubMu.Unlock()
+
+	c := chub
+	res := make(chan error, 1)
+	go func() {
+		res <- r.call(c)
+	}()
+
+	var err error
+	select {
+	case err = <-res:
+	case <-time.After(r.timeout):
+		err = errTimeout
+	}
+	if err != nil && !chubby.IsServerError(err) &&
+		!errors.Is(err, chubby.ErrNotConnected) {
+
+		c.Close()
+	}
+
+	return err
+}
+
+// completeRequest is called by the UI goroutine when the request
+// is executed.
+func completeRequest(r *request, err error) {
+	for i, p := range pending {
+		if p == r {
+			pending = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		if r.done != nil {
+			r.done()
+		}
+	case errors.Is(err, chubby.ErrNotConnected):
+		showMessage("not connected")
+	case errors.Is(err, errTimeout), chubby.IsServerError(err):
+		showMessage("%s: %s", r.name, err)
+	default:
+		// Network error, status bar shows reconnection state.
+	}
+}
+
+// updatePending shows spinner in the message window while the oldest
+// pending request takes too long.
+func updatePending() {
+	if len(pending) == 0 {
+		if spinnerShown {
+			msgWnd.Clear()
+			spinnerShown = false
+		}
+		return
+	}
+	d := time.Since(pending[0].started)
+	if d < pendingDelay {
+		return
+	}
+	frame := int(d/(100*time.Millisecond)) % len(spinnerFrames)
+	msgWnd.Update("%s %s", spinnerFrames[frame], pending[0].name)
+	spinnerShown = true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vchimishuk/chubby"
+)
+
+var startQueue sync.Once
+
+func TestQueueOverflow(t *testing.T) {
+	startQueue.Do(func() { go serveRequests() })
+
+	// More requests than both queues can hold, so the queue goroutine
+	// gets blocked posting results while the requests are submitted.
+	n := cap(requests) + cap(uiEvents) + 16
+	queued, completed := 0, 0
+	for i := 0; i < n; i++ {
+		r := &request{
+			timeout: time.Second,
+			call: func(c *chubby.Chubby) error {
+				return nil
+			},
+			done: func() {
+				completed++
+			},
+			started: time.Now(),
+		}
+		if enqueue(r) {
+			queued++
+		}
+	}
+	if queued == n {
+		t.Errorf("Requests expected to be rejected by the full queue.")
+	}
+
+	timeout := time.After(5 * time.Second)
+	for completed < queued {
+		select {
+		case e := <-uiEvents:
+			e()
+		case <-timeout:
+			t.Fatalf("Requests completed: %d of %d", completed, queued)
+		}
+	}
+}
+
+func TestQueueDone(t *testing.T) {
+	startQueue.Do(func() { go serveRequests() })
+
+	var done []int
+	for i := 0; i < 10; i++ {
+		i := i
+		submit("test", func(c *chubby.Chubby) error {
+			return nil
+		}, func() {
+			done = append(done, i)
+		})
+	}
+
+	timeout := time.After(5 * time.Second)
+	for len(pending) > 0 {
+		select {
+		case e := <-uiEvents:
+			e()
+		case <-timeout:
+			t.Fatalf("Requests pending: %d", len(pending))
+		}
+	}
+	for i, d := range done {
+		if d != i {
+			t.Fatalf("Done callbacks mismatch: %v", done)
+		}
+	}
+	if len(done) != 10 {
+		t.Errorf("Done callbacks expected 10, got %d", len(done))
+	}
+}
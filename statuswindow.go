@@ -21,10 +21,12 @@ func NewStatusWindow(w, y, x int) (*StatusWindow, error) {
 	panel.SetColor(config.ColorStatus)
 
 	return &StatusWindow{
-		panel:       panel,
-		playingFmtr: format.NewFormatter(config.FormatStatusPlaying),
-		pausedFmtr:  format.NewFormatter(config.FormatStatusPaused),
-		stoppedFmtr: format.NewFormatter(""),
+		panel: panel,
+		playingFmtr: format.NewFormatter(config.FormatStatusPlaying,
+			config.PlayerVerbs),
+		pausedFmtr: format.NewFormatter(config.FormatStatusPaused,
+			config.PlayerVerbs),
+		stoppedFmtr: format.NewFormatter("", config.PlayerVerbs),
 	}, nil
 }
 
@@ -32,6 +34,11 @@ func (w *StatusWindow) Delete() {
 	w.panel.Delete()
 }
 
+// SetText displays the text instead of the player status.
+func (w *StatusWindow) SetText(s string) {
+	w.panel.SetText(s)
+}
+
 func (w *StatusWindow) Update(state chubby.State, data map[string]string) {
 	var fmtr format.Formatter
 
@@ -43,5 +50,5 @@ func (w *StatusWindow) Update(state chubby.State, data map[string]string) {
 		fmtr = w.stoppedFmtr
 	}
 
-	w.panel.SetText(fmtr.Format(data, w.panel.Width()))
+	w.panel.SetSpans(fmtr.FormatSpans(data, w.panel.Width()))
 }
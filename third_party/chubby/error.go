@@ -0,0 +1,38 @@
+// Copyright 2023 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of chubby.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package chubby
+
+import "errors"
+
+type ServerError struct {
+	msg string
+}
+
+func (e ServerError) Error() string {
+	return e.msg
+}
+
+func newServerError(msg string) ServerError {
+	return ServerError{msg}
+}
+
+func IsServerError(err error) bool {
+	var c ServerError
+
+	return errors.As(err, &c)
+}
@@ -0,0 +1,3 @@
+module github.com/vchimishuk/chubby
+
+go 1.20
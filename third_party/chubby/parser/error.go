@@ -0,0 +1,33 @@
+// Copyright 2016 Viacheslav Chimishuk <vchimishuk@yandex.ru>
+//
+// This file is part of chubby.
+//
+// Chub is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Chub is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Chub. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import "fmt"
+
+type Error struct {
+	Position int
+	Message  string
+}
+
+func newError(pos int, msg string) *Error {
+	return &Error{Position: pos, Message: msg}
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s at pos %d", err.Message, err.Position)
+}
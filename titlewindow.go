@@ -19,12 +19,12 @@ func NewTitleWindow(w, y, x int) (*TitleWindow, error) {
 
 	return &TitleWindow{
 		panel: panel,
-		fmtr:  format.NewFormatter(config.FormatTitle),
+		fmtr:  format.NewFormatter(config.FormatTitle, config.PlayerVerbs),
 	}, nil
 }
 
 func (w *TitleWindow) Update(data map[string]string) {
-	w.panel.SetText(w.fmtr.Format(data, w.panel.Width()))
+	w.panel.SetSpans(w.fmtr.FormatSpans(data, w.panel.Width()))
 }
 
 func (w *TitleWindow) Delete() {